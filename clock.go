@@ -1,6 +1,9 @@
 package goconnpool
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // Clock interface is required to emulate system clock.
 type Clock interface {
@@ -24,3 +27,20 @@ func (SystemClock) Since(tm time.Time) time.Duration {
 func (SystemClock) After(tm time.Duration) <-chan time.Time {
 	return time.After(tm)
 }
+
+type clockKey struct{}
+
+// withClock passes the clock of the pool to the dialer.
+func withClock(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, c)
+}
+
+// contextClock returns the clock of the pool which dials (see Config.Clock).
+// SystemClock is returned if the dialer is used outside of the pool.
+func contextClock(ctx context.Context) Clock {
+	if c, ok := ctx.Value(clockKey{}).(Clock); ok && c != nil {
+		return c
+	}
+
+	return SystemClock{}
+}
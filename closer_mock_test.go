@@ -1,5 +1,10 @@
 // Code generated by MockGen. DO NOT EDIT.
 // Source: server_test.go
+//
+// Generated by this command:
+//
+//	mockgen -source=server_test.go -package=goconnpool -destination=closer_mock_test.go
+//
 
 // Package goconnpool is a generated GoMock package.
 package goconnpool
@@ -7,38 +12,41 @@ package goconnpool
 import (
 	reflect "reflect"
 
-	gomock "github.com/golang/mock/gomock"
+	gomock "go.uber.org/mock/gomock"
 )
 
-// Mockcloser is a mock of closer interface
+// Mockcloser is a mock of closer interface.
 type Mockcloser struct {
 	ctrl     *gomock.Controller
 	recorder *MockcloserMockRecorder
+	isgomock struct{}
 }
 
-// MockcloserMockRecorder is the mock recorder for Mockcloser
+// MockcloserMockRecorder is the mock recorder for Mockcloser.
 type MockcloserMockRecorder struct {
 	mock *Mockcloser
 }
 
-// NewMockcloser creates a new mock instance
+// NewMockcloser creates a new mock instance.
 func NewMockcloser(ctrl *gomock.Controller) *Mockcloser {
 	mock := &Mockcloser{ctrl: ctrl}
 	mock.recorder = &MockcloserMockRecorder{mock}
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
+// EXPECT returns an object that allows the caller to indicate expected use.
 func (m *Mockcloser) EXPECT() *MockcloserMockRecorder {
 	return m.recorder
 }
 
-// Close mocks base method
+// Close mocks base method.
 func (m *Mockcloser) Close() {
+	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "Close")
 }
 
-// Close indicates an expected call of Close
+// Close indicates an expected call of Close.
 func (mr *MockcloserMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*Mockcloser)(nil).Close))
 }
@@ -1,11 +1,18 @@
 package goconnpool
 
-import "time"
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
 
 const (
 	// DefaultMaxConnsPerServer is the default value for MaxConnsPerServer config variable.
 	DefaultMaxConnsPerServer = 1
 
+	// DefaultMaxStreamsPerConn is the default value for MaxStreamsPerConn config variable.
+	DefaultMaxStreamsPerConn = 1
+
 	// DefaultMaxRPS is the default value for MaxRPS config variable.
 	DefaultMaxRPS = 100
 
@@ -27,6 +34,15 @@ type Config struct {
 	// Use math.MaxInt32 to disable this limit.
 	MaxConnsPerServer int
 
+	// MaxStreamsPerConn declares maximum number of borrowers which could use one connection concurrently
+	// (multiplexed mode for protocols like HTTP/2 or multiplexed RPC).
+	// Each OpenConn call leases the connection: the connection with free streams is leased before the new one
+	// is dialed. Conn.ReturnToPool() releases the lease. Conn.Close() marks the connection broken: it isn't
+	// leased anymore and it is closed when all leases are released.
+	//
+	// Default is DefaultMaxStreamsPerConn (each connection is owned by one borrower).
+	MaxStreamsPerConn int
+
 	// MaxRPS declares maximum number of requests which could be sent into one server (XXX: not connection)
 	// per second.
 	// Frankly this value regulates only a number of OpenConn calls: goconnpool can't regulate number of real
@@ -63,6 +79,9 @@ type Config struct {
 
 	// Logger could be used to view some messages, printed by the library.
 	// This messages could contain information about server status, about some errors or something else.
+	//
+	// Implement StructuredLogger to receive messages with structured fields (and debug messages).
+	// Use NewSlogLogger to pass messages into log/slog.
 	Logger Logger
 
 	// Dialer is used to dial to the specific server.
@@ -70,9 +89,42 @@ type Config struct {
 	// Required because some protocols (like thrift or websockets) can't be used with raw net.Conn object:
 	// they establishes connection in some specific way.
 	//
-	// TCPDialer is the default.
+	// HappyEyeballsDialer (with the pool Clock and backoff intervals) is the default: IPs of hostnames get
+	// their own backoff. Use TLSDialer for TLS servers and ProxyDialer for servers behind the proxy.
 	Dialer Dialer
 
+	// ConnWrapper is applied to each connection dialed by ConnPool: it could be used to observe the traffic
+	// (see CountBytes, WithIODeadlines and MarkBrokenOnError). Conn.OriginalConn() still returns the connection
+	// returned by Dialer.
+	// Connections aren't wrapped by default.
+	ConnWrapper ConnWrapper
+
+	// Hooks could be used to receive notifications about the pool lifecycle events.
+	// No hooks are invoked by default.
+	Hooks *Hooks
+
+	// LeakThreshold enables connections leak detection.
+	//
+	// Connections owned by the user longer than this threshold are reported with Logger and Hooks.OnConnLeaked
+	// (leaks are checked on each OpenConn* call). Stack trace of the call returned the connection is recorded
+	// and shown in Stats.
+	//
+	// Connections garbage collected without Close() or ReturnToPool() call are closed and their slots are
+	// released. XXX: don't drop the Conn while using the connection returned by OriginalConn().
+	//
+	// Stack traces recording isn't free: leak detection is disabled by default.
+	LeakThreshold time.Duration
+
+	// ErrorClassifier is used by ConnPool.Do to decide what to do with the connection when the callback
+	// was failed.
+	//
+	// Each error is treated as ErrorClassConnBroken by default.
+	ErrorClassifier ErrorClassifier
+
+	// RetryBudget limits the number of retries made by the pool (see RetryBudget for more info).
+	// Retries aren't limited by default.
+	RetryBudget *RetryBudget
+
 	// backoffRandomizationFactor is used in tests only: default randomization factor is used in produnction.
 	// See https://godoc.org/github.com/cenkalti/backoff#ExponentialBackOff for more info
 	backoffRandomizationFactor *float64
@@ -93,10 +145,12 @@ func NewConfig(p FlagsParser) *Config {
 
 	p.IntVar(&c.MaxConnsPerServer, "max_conns_per_server", DefaultMaxConnsPerServer,
 		"Maximum number of opened connections per server")
+	p.IntVar(&c.MaxStreamsPerConn, "max_streams_per_conn", DefaultMaxStreamsPerConn,
+		"Maximum number of concurrent borrowers of one connection")
 	p.IntVar(&c.MaxRPS, "max_rps", DefaultMaxRPS,
 		"Maximim number of requests per one server per second")
 
-	p.DurationVar(&c.ConnectTimeout, "connect_timeout", 0,
+	p.DurationVar(&c.ConnectTimeout, "connect_timeout", DefaultConnectTimeout,
 		"Maximum amount of time a dial will wait for a connect to complete")
 
 	p.DurationVar(&c.InitialBackoffInterval, "init_backoff_interval", DefaultInitBackoffInterval,
@@ -107,11 +161,49 @@ func NewConfig(p FlagsParser) *Config {
 	return &c
 }
 
+// Validate checks the configuration: negative limits and intervals are rejected as well as MaxBackoffInterval
+// less than InitialBackoffInterval (defaults are taken into account). Zero values are valid: defaults are used.
+func (c Config) Validate() error {
+	for _, f := range []struct {
+		name     string
+		negative bool
+		value    interface{}
+	}{
+		{"MaxConnsPerServer", c.MaxConnsPerServer < 0, c.MaxConnsPerServer},
+		{"MaxStreamsPerConn", c.MaxStreamsPerConn < 0, c.MaxStreamsPerConn},
+		{"MaxRPS", c.MaxRPS < 0, c.MaxRPS},
+		{"ConnectTimeout", c.ConnectTimeout < 0, c.ConnectTimeout},
+		{"InitialBackoffInterval", c.InitialBackoffInterval < 0, c.InitialBackoffInterval},
+		{"MaxBackoffInterval", c.MaxBackoffInterval < 0, c.MaxBackoffInterval},
+		{"LeakThreshold", c.LeakThreshold < 0, c.LeakThreshold},
+	} {
+		if f.negative {
+			return errors.Errorf("invalid config: %s should not be negative (got %v)", f.name, f.value)
+		}
+	}
+
+	if err := c.RetryBudget.validate(); err != nil {
+		return errors.Wrap(err, "invalid config")
+	}
+
+	d := c.withDefaults()
+	if d.MaxBackoffInterval < d.InitialBackoffInterval {
+		return errors.Errorf("invalid config: MaxBackoffInterval (%s) should not be less than "+
+			"InitialBackoffInterval (%s)", d.MaxBackoffInterval, d.InitialBackoffInterval)
+	}
+
+	return nil
+}
+
 func (c Config) withDefaults() Config {
 	if c.MaxConnsPerServer == 0 {
 		c.MaxConnsPerServer = DefaultMaxConnsPerServer
 	}
 
+	if c.MaxStreamsPerConn == 0 {
+		c.MaxStreamsPerConn = DefaultMaxStreamsPerConn
+	}
+
 	if c.MaxRPS == 0 {
 		c.MaxRPS = DefaultMaxRPS
 	}
@@ -137,8 +229,30 @@ func (c Config) withDefaults() Config {
 	}
 
 	if c.Dialer == nil {
-		c.Dialer = &TCPDialer{}
+		c.Dialer = DefaultDialer(c)
 	}
 
 	return c
 }
+
+// DefaultDialer returns the dialer used by the pool if Config.Dialer is nil: HappyEyeballsDialer with the clock
+// and backoff intervals of the config passed (zero values are replaced with defaults).
+func DefaultDialer(cfg Config) Dialer {
+	if cfg.InitialBackoffInterval == 0 {
+		cfg.InitialBackoffInterval = DefaultInitBackoffInterval
+	}
+
+	if cfg.MaxBackoffInterval == 0 {
+		cfg.MaxBackoffInterval = DefaultMaxBackoffInterval
+	}
+
+	if cfg.Clock == nil {
+		cfg.Clock = SystemClock{}
+	}
+
+	return &HappyEyeballsDialer{
+		InitialBackoffInterval: cfg.InitialBackoffInterval,
+		MaxBackoffInterval:     cfg.MaxBackoffInterval,
+		Clock:                  cfg.Clock,
+	}
+}
@@ -1,6 +1,7 @@
 // Package goconnpool implements connections pool with ratelimits and backoff for broken connections.
 //
 // Connection returned by the pool is protocol-independent.
+// Resources of any other type (like gRPC or thrift clients) could be pooled using the generic Pool (see NewPool).
 //
 package goconnpool
 
@@ -28,6 +29,10 @@ type Conn interface {
 	//
 	// XXX: Returned connection shouldn't be closed.
 	OriginalConn() net.Conn
+
+	// ServerAddr returns the address of the registered server the connection was opened to.
+	// This address is the same address used into RegisterServer call.
+	ServerAddr() string
 }
 
 // ConnPool is the base interface to interact with user.
@@ -52,20 +57,123 @@ type ConnPool interface {
 
 	// OpenConn does same things as OpenConnNonBlock, but it blocks until new connection
 	// will be established. This process could be cancelled using the context.
+	//
+	// ErrRetryBudgetExhausted is returned (wrapped) if re-attempts were suppressed by Config.RetryBudget.
 	OpenConn(ctx context.Context) (Conn, error)
 
 	// OpenConnWithTimeout does same things as OpenConn, but it stops to wait new connection after timeout.
 	OpenConnWithTimeout(ctx context.Context, timeout time.Duration) (Conn, error)
 
+	// OpenConnToServer does same things as OpenConnNonBlock, but the connection is opened only to
+	// the registered server with address passed. Could be used to integrate the pool with some
+	// other balancer (like gRPC).
+	//
+	// ErrUnknownServer is returned if the server with address passed wasn't registered.
+	OpenConnToServer(ctx context.Context, addr string) (Conn, error)
+
+	// OpenConnToServerBlock does same things as OpenConnToServer, but it waits while the server is ratelimited
+	// (like OpenConn does). Error is returned immediately if the server is down.
+	OpenConnToServerBlock(ctx context.Context, addr string) (Conn, error)
+
+	// Do borrows the connection (like OpenConn does) and passes it into fn.
+	//
+	// Connection is returned into pool if fn succeeded. Otherwise the connection is released according to
+	// the class of the error returned (see Config.ErrorClassifier): by default it is closed.
+	// fn shouldn't close the connection or return it into pool by itself.
+	//
+	// Error returned by fn is returned as is.
+	Do(ctx context.Context, fn func(Conn) error) error
+
+	// DoWithRetry does same things as Do, but fn is retried according to the policy passed.
+	//
+	// Each attempt is made using the server which wasn't failed during previous attempts
+	// (failed servers are used only if other servers can't return the connection).
+	//
+	// Error returned by the last attempt is returned.
+	// ErrRetryBudgetExhausted is returned (wrapped) if the retry was suppressed by Config.RetryBudget.
+	DoWithRetry(ctx context.Context, policy RetryPolicy, fn func(Conn) error) error
+
+	// DoHedged does same things as Do, but if fn doesn't return during hedgeDelay, one more fn is started
+	// using the connection to another server (up to maxHedges additional requests).
+	// Could be used to reduce tail latency of idempotent requests.
+	//
+	// Result of the first succeeded request is returned. Context passed into other requests is cancelled
+	// and their connections are closed (they could be in the middle of the response).
+	// Hedged requests are sent only if the server available right now is found: MaxRPS limits are respected.
+	//
+	// If all requests are failed, error returned by the last one is returned.
+	// Error is returned without fn call if maxHedges is negative or if hedgeDelay isn't positive
+	// while hedged requests are allowed.
+	DoHedged(ctx context.Context, hedgeDelay time.Duration, maxHedges int, fn func(context.Context, Conn) error) error
+
 	// RegisterServer registers new server in connections pool.
 	// This server stands into round-robin queue to be used during OpenConn call.
 	//
+	// Address format depends on the Dialer used (see HappyEyeballsDialer for the default one).
+	// Error is returned if the Dialer implements AddressValidator and the address is invalid.
+	//
+	// Options could be used to override the pool configuration for this server:
+	//
+	//	pool.RegisterServer("primary:5432", goconnpool.ServerMaxRPS(1000))
+	//	pool.RegisterServer("remote-replica:5432", goconnpool.ServerConnectTimeout(time.Second))
+	//
+	// Error is returned if the effective config of the server (the pool config with options applied) is invalid
+	// (see Config.Validate).
+	//
 	// This operation is a part of initialization.
 	// Don't try to call it in runtime: not thread safe.
-	RegisterServer(addr string)
+	RegisterServer(addr string, opts ...ServerOption) error
+
+	// UpdateConfig atomically applies new MaxConnsPerServer, MaxStreamsPerConn, MaxRPS, ConnectTimeout,
+	// backoff intervals, Dialer and ConnWrapper to the registered servers. Per-server overrides passed into
+	// RegisterServer are kept. Zero values are replaced with defaults as NewConnPool does.
+	//
+	// Borrowed connections aren't dropped; excess idle connections are closed if limits were shrunk.
+	// New dialer is used for new connections only; current dialer is kept if Config.Dialer is nil. Other config fields (Clock, Logger, Hooks, ...) aren't changed.
+	//
+	// Error is returned if the config passed (or the config of some server with its overrides applied) is
+	// invalid (see Config.Validate): nothing is changed then.
+	UpdateConfig(cfg Config) error
+
+	// Stats returns the snapshot of the registered servers state.
+	// Could be used to export pool metrics into some monitoring system.
+	Stats() Stats
+
+	// SetServerMode changes the mode of the registered server.
+	// Could be used to disable the server for maintenance.
+	//
+	// ErrUnknownServer is returned if the server with address passed wasn't registered.
+	SetServerMode(addr string, mode ServerMode) error
 }
 
+// ServerMode regulates whether the registered server could be used to open new connections.
+type ServerMode string
+
+const (
+	// ServerModeAuto is the default mode: server is used while it is alive.
+	// Setting this mode also resets the server backoff state (i.e. marks the server up).
+	ServerModeAuto ServerMode = "auto"
+
+	// ServerModeDown marks the server down: new connections aren't returned until ServerModeAuto set.
+	// Opened connections are kept and could be reused after the server is up.
+	ServerModeDown ServerMode = "down"
+
+	// ServerModeDrain marks the server down and closes its connections:
+	// idle connections are closed immediately, borrowed connections are closed when returned into pool.
+	ServerModeDrain ServerMode = "drain"
+)
+
 // NewConnPool creates new pool with configuration passed.
 func NewConnPool(cfg Config) ConnPool {
 	return newConnPool(cfg)
 }
+
+// NewConnPoolE creates new pool with configuration passed like NewConnPool does,
+// but the configuration is checked with Config.Validate first.
+func NewConnPoolE(cfg Config) (ConnPool, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return newConnPool(cfg), nil
+}
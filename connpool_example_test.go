@@ -18,8 +18,12 @@ func ExampleNewConnPool_base() {
 	pool := NewConnPool(*cfg)
 
 	// Register some servers
-	pool.RegisterServer("127.0.0.1:1234")
-	pool.RegisterServer("8.8.8.8:1234")
+	for _, addr := range []string{"127.0.0.1:1234", "8.8.8.8:1234"} {
+		if err := pool.RegisterServer(addr); err != nil {
+			// Invalid address or server options
+			return
+		}
+	}
 
 	for i := 0; i < 10; i++ {
 		cn, err := pool.OpenConnNonBlock(context.Background()) // Context could be cancelable here
@@ -49,11 +53,15 @@ func ExampleNewConnPool_httpRequest() {
 	pool := NewConnPool(*cfg)
 
 	// Register some servers
-	pool.RegisterServer("127.0.0.1:1234")
+	if err := pool.RegisterServer("127.0.0.1:1234"); err != nil {
+		// Invalid address or server options
+		return
+	}
 
 	cn, _ := pool.OpenConn(context.Background()) // success connection
 	defer cn.Close()
 
+	// Use httppool.NewTransport to send requests with net/http client.
 	// You could implement your own transport in the same way:
 	// https://golang.org/pkg/net/http/#RoundTripper
 	req, _ := http.NewRequest(http.MethodGet, "/some", nil)
@@ -71,8 +79,12 @@ func ExampleNewConnPool_blockingCalls() {
 	pool := NewConnPool(*cfg)
 
 	// Register some servers
-	pool.RegisterServer("127.0.0.1:1111")
-	pool.RegisterServer("127.0.0.1:2222")
+	for _, addr := range []string{"127.0.0.1:1111", "127.0.0.1:2222"} {
+		if err := pool.RegisterServer(addr); err != nil {
+			// Invalid address or server options
+			return
+		}
+	}
 
 	// It is simplier to use WithTimeout() here ;)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -132,7 +144,10 @@ func ExampleNewConnPool_dialer() {
 		Dialer: MyDialer{},
 	})
 
-	p.RegisterServer("google.com")
+	if err := p.RegisterServer("google.com"); err != nil {
+		// MyDialer doesn't validate addresses: this error isn't expected here
+		return
+	}
 
 	cn, _ := p.OpenConn(context.Background())
 	origCn := cn.OriginalConn().(MyConn)
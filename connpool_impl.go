@@ -0,0 +1,145 @@
+package goconnpool
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// connPool is the Pool of net.Conn.
+type connPool struct {
+	*pool[net.Conn]
+	traffic *trafficRegistry
+}
+
+func newConnPool(cfg Config) *connPool {
+	cfg = cfg.withDefaults()
+	traffic := &trafficRegistry{}
+
+	return &connPool{
+		pool: newPool[net.Conn](cfg, dialerFactory{
+			d:       cfg.Dialer,
+			wrap:    cfg.ConnWrapper,
+			traffic: traffic,
+		}),
+		traffic: traffic,
+	}
+}
+
+func (p *connPool) OpenConn(ctx context.Context) (Conn, error) {
+	r, err := p.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newServerConn(r), nil
+}
+
+func (p *connPool) OpenConnWithTimeout(ctx context.Context, timeout time.Duration) (Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return p.OpenConn(ctx)
+}
+
+func (p *connPool) OpenConnNonBlock(ctx context.Context) (Conn, error) {
+	r, err := p.getNonBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newServerConn(r), nil
+}
+
+func (p *connPool) OpenConnToServer(ctx context.Context, addr string) (Conn, error) {
+	r, err := p.getFromServer(ctx, addr, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return newServerConn(r), nil
+}
+
+func (p *connPool) OpenConnToServerBlock(ctx context.Context, addr string) (Conn, error) {
+	r, err := p.getFromServer(ctx, addr, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return newServerConn(r), nil
+}
+
+func (p *connPool) UpdateConfig(cfg Config) error {
+	if cfg.Dialer == nil {
+		// current dialer (and its state, see HappyEyeballsDialer) is kept
+		cfg.Dialer = p.serversConfig().Dialer
+	}
+
+	cfg = cfg.withDefaults()
+	return p.updateConfig(cfg, dialerFactory{
+		d:       cfg.Dialer,
+		wrap:    cfg.ConnWrapper,
+		traffic: p.traffic,
+	})
+}
+
+func (p *connPool) Stats() Stats {
+	st := p.pool.Stats()
+	for i := range st.Servers {
+		t := p.traffic.get(st.Servers[i].Address)
+		st.Servers[i].BytesRead = atomic.LoadUint64(&t.read)
+		st.Servers[i].BytesWritten = atomic.LoadUint64(&t.written)
+	}
+
+	return st
+}
+
+func (p *connPool) Do(ctx context.Context, fn func(Conn) error) error {
+	return p.doWith(ctx, func(r *resource[net.Conn]) error {
+		return fn(newServerConn(r))
+	})
+}
+
+func (p *connPool) DoWithRetry(ctx context.Context, policy RetryPolicy, fn func(Conn) error) error {
+	return p.doWithRetry(ctx, policy, func(r *resource[net.Conn]) error {
+		return fn(newServerConn(r))
+	})
+}
+
+func (p *connPool) DoHedged(ctx context.Context, hedgeDelay time.Duration, maxHedges int,
+	fn func(context.Context, Conn) error) error {
+
+	return p.doHedged(ctx, hedgeDelay, maxHedges, func(ctx context.Context, r *resource[net.Conn]) error {
+		return fn(ctx, newServerConn(r))
+	})
+}
+
+// serverConn implements Conn interface over the pooled net.Conn.
+type serverConn struct {
+	net.Conn
+	*resource[net.Conn]
+
+	orig net.Conn
+}
+
+func newServerConn(r *resource[net.Conn]) *serverConn {
+	cn := &serverConn{
+		Conn:     r.value,
+		resource: r,
+		orig:     r.value,
+	}
+
+	if w, ok := r.value.(*wrappedConn); ok {
+		cn.orig = w.orig
+	}
+
+	return cn
+}
+
+func (cn *serverConn) Close() error {
+	return cn.resource.Close()
+}
+
+func (cn *serverConn) OriginalConn() net.Conn {
+	return cn.orig
+}
@@ -0,0 +1,231 @@
+package goconnpool
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConnInfo describes the connection passed into ConnWrapper.
+type ConnInfo struct {
+	// ServerAddr is the address of the registered server the connection was dialed to.
+	ServerAddr string
+
+	traffic *serverTraffic
+	state   *connState
+}
+
+// MarkBroken marks the connection broken: it will be closed instead of being returned into the pool
+// on Conn.ReturnToPool() call.
+func (i ConnInfo) MarkBroken() {
+	if i.state != nil {
+		atomic.StoreInt32(&i.state.broken, 1)
+	}
+}
+
+// ConnWrapper wraps each connection dialed by ConnPool (see Config.ConnWrapper).
+// Use ChainConnWrappers to apply several wrappers.
+type ConnWrapper func(cn net.Conn, info ConnInfo) net.Conn
+
+// ChainConnWrappers combines wrappers passed into one. The first wrapper is the outermost one.
+func ChainConnWrappers(ws ...ConnWrapper) ConnWrapper {
+	return func(cn net.Conn, info ConnInfo) net.Conn {
+		for i := len(ws) - 1; i >= 0; i-- {
+			cn = ws[i](cn, info)
+		}
+
+		return cn
+	}
+}
+
+// CountBytes returns the wrapper counting bytes read and written per server.
+// Counters are exposed in ServerStats.
+func CountBytes() ConnWrapper {
+	return func(cn net.Conn, info ConnInfo) net.Conn {
+		if info.traffic == nil {
+			return cn
+		}
+
+		return &countingConn{Conn: cn, traffic: info.traffic}
+	}
+}
+
+type countingConn struct {
+	net.Conn
+	traffic *serverTraffic
+}
+
+func (cn *countingConn) Read(b []byte) (int, error) {
+	n, err := cn.Conn.Read(b)
+	atomic.AddUint64(&cn.traffic.read, uint64(n))
+	return n, err
+}
+
+func (cn *countingConn) Write(b []byte) (int, error) {
+	n, err := cn.Conn.Write(b)
+	atomic.AddUint64(&cn.traffic.written, uint64(n))
+	return n, err
+}
+
+// WithIODeadlines returns the wrapper setting the deadline before each Read (Write) call:
+// each operation should complete within readTimeout (writeTimeout).
+// Zero timeout disables the deadline of the corresponding operation.
+//
+// Deadlines set by the caller (SetDeadline, SetReadDeadline, SetWriteDeadline) are kept:
+// the earliest of the caller deadline and the operation timeout is applied.
+func WithIODeadlines(readTimeout, writeTimeout time.Duration) ConnWrapper {
+	return func(cn net.Conn, info ConnInfo) net.Conn {
+		return &deadlineConn{Conn: cn, read: readTimeout, write: writeTimeout}
+	}
+}
+
+type deadlineConn struct {
+	net.Conn
+	read, write time.Duration
+
+	mu            sync.Mutex
+	readDeadline  time.Time // set by the caller
+	writeDeadline time.Time // set by the caller
+}
+
+func (cn *deadlineConn) SetDeadline(t time.Time) error {
+	cn.mu.Lock()
+	cn.readDeadline, cn.writeDeadline = t, t
+	cn.mu.Unlock()
+
+	return cn.Conn.SetDeadline(t)
+}
+
+func (cn *deadlineConn) SetReadDeadline(t time.Time) error {
+	cn.mu.Lock()
+	cn.readDeadline = t
+	cn.mu.Unlock()
+
+	return cn.Conn.SetReadDeadline(t)
+}
+
+func (cn *deadlineConn) SetWriteDeadline(t time.Time) error {
+	cn.mu.Lock()
+	cn.writeDeadline = t
+	cn.mu.Unlock()
+
+	return cn.Conn.SetWriteDeadline(t)
+}
+
+func (cn *deadlineConn) Read(b []byte) (int, error) {
+	if cn.read > 0 {
+		cn.mu.Lock()
+		deadline := earliestDeadline(cn.readDeadline, time.Now().Add(cn.read))
+		cn.mu.Unlock()
+
+		if err := cn.Conn.SetReadDeadline(deadline); err != nil {
+			return 0, err
+		}
+	}
+
+	return cn.Conn.Read(b)
+}
+
+func (cn *deadlineConn) Write(b []byte) (int, error) {
+	if cn.write > 0 {
+		cn.mu.Lock()
+		deadline := earliestDeadline(cn.writeDeadline, time.Now().Add(cn.write))
+		cn.mu.Unlock()
+
+		if err := cn.Conn.SetWriteDeadline(deadline); err != nil {
+			return 0, err
+		}
+	}
+
+	return cn.Conn.Write(b)
+}
+
+// earliestDeadline returns the earliest deadline. Zero deadline set by the caller means no deadline.
+func earliestDeadline(caller, timeout time.Time) time.Time {
+	if caller.IsZero() || timeout.Before(caller) {
+		return timeout
+	}
+
+	return caller
+}
+
+// MarkBrokenOnError returns the wrapper marking the connection broken on any Read or Write error
+// (including timeouts and EOF): the state of the protocol is unknown after such errors,
+// so the connection is closed on Conn.ReturnToPool() call instead of being reused.
+func MarkBrokenOnError() ConnWrapper {
+	return func(cn net.Conn, info ConnInfo) net.Conn {
+		return &errorDetectingConn{Conn: cn, info: info}
+	}
+}
+
+type errorDetectingConn struct {
+	net.Conn
+	info ConnInfo
+}
+
+func (cn *errorDetectingConn) Read(b []byte) (int, error) {
+	n, err := cn.Conn.Read(b)
+	if err != nil {
+		cn.info.MarkBroken()
+	}
+
+	return n, err
+}
+
+func (cn *errorDetectingConn) Write(b []byte) (int, error) {
+	n, err := cn.Conn.Write(b)
+	if err != nil {
+		cn.info.MarkBroken()
+	}
+
+	return n, err
+}
+
+type serverTraffic struct {
+	read    uint64
+	written uint64
+}
+
+type connState struct {
+	broken int32
+}
+
+// trafficRegistry holds traffic counters of the servers.
+type trafficRegistry struct {
+	mu      sync.Mutex
+	servers map[string]*serverTraffic
+}
+
+func (r *trafficRegistry) get(addr string) *serverTraffic {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.servers == nil {
+		r.servers = map[string]*serverTraffic{}
+	}
+
+	t, ok := r.servers[addr]
+	if !ok {
+		t = &serverTraffic{}
+		r.servers[addr] = t
+	}
+
+	return t
+}
+
+// brokenConn is implemented by connections which could be marked broken by the ConnWrapper (see wrappedConn).
+type brokenConn interface {
+	broken() bool
+}
+
+// wrappedConn is the connection wrapped with Config.ConnWrapper.
+type wrappedConn struct {
+	net.Conn
+	orig  net.Conn
+	state *connState
+}
+
+func (cn *wrappedConn) broken() bool {
+	return atomic.LoadInt32(&cn.state.broken) != 0
+}
@@ -0,0 +1,101 @@
+package goconnpool
+
+import (
+	"context"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnWrapper(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	addr := echoTestServer(t)
+
+	var wrapped []string
+	p := NewConnPool(Config{
+		MaxRPS: math.MaxInt32,
+		ConnWrapper: ChainConnWrappers(
+			func(cn net.Conn, info ConnInfo) net.Conn {
+				wrapped = append(wrapped, info.ServerAddr)
+				return cn
+			},
+			CountBytes(),
+			MarkBrokenOnError(),
+			WithIODeadlines(50*time.Millisecond, time.Second),
+		),
+	})
+	ass.NoError(p.RegisterServer(addr))
+
+	cn, err := p.OpenConn(context.Background())
+	ass.NoError(err)
+	ass.Equal([]string{addr}, wrapped)
+	ass.IsType(&net.TCPConn{}, cn.OriginalConn())
+
+	_, err = cn.Write([]byte("ping"))
+	ass.NoError(err)
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(cn, buf)
+	ass.NoError(err)
+
+	ass.NoError(cn.ReturnToPool())
+
+	st := p.Stats().Servers[0]
+	ass.EqualValues(4, st.BytesRead)
+	ass.EqualValues(4, st.BytesWritten)
+	ass.Equal(1, st.IdleConns)
+
+	// read deadline is exceeded: connection is broken
+	cn, err = p.OpenConn(context.Background())
+	ass.NoError(err)
+
+	_, err = cn.Read(buf)
+	ass.Error(err)
+	ass.True(err.(net.Error).Timeout())
+
+	ass.NoError(cn.ReturnToPool())
+	ass.Equal(0, p.Stats().Servers[0].OpenConns)
+	ass.Len(wrapped, 1)
+}
+
+func TestIODeadlinesKeepCallerDeadlines(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	client, server := net.Pipe()
+	defer server.Close()
+
+	cn := WithIODeadlines(time.Hour, time.Hour)(client, ConnInfo{})
+	defer cn.Close()
+
+	// caller deadline is earlier than the operation timeout: it is applied
+	ass.NoError(cn.SetReadDeadline(time.Now().Add(10 * time.Millisecond)))
+
+	_, err := cn.Read(make([]byte, 1))
+	ass.Error(err)
+	ass.True(err.(net.Error).Timeout())
+
+	ass.NoError(cn.SetDeadline(time.Now().Add(10 * time.Millisecond)))
+
+	_, err = cn.Write([]byte("ping"))
+	ass.Error(err)
+	ass.True(err.(net.Error).Timeout())
+
+	// operation timeout is earlier than the caller deadline: it is applied
+	cn = WithIODeadlines(10*time.Millisecond, 0)(client, ConnInfo{})
+	ass.NoError(cn.SetReadDeadline(time.Now().Add(time.Hour)))
+
+	_, err = cn.Read(make([]byte, 1))
+	ass.Error(err)
+	ass.True(err.(net.Error).Timeout())
+
+	// zero caller deadline means no deadline
+	ass.Equal(time.Unix(1, 0), earliestDeadline(time.Time{}, time.Unix(1, 0)))
+	ass.Equal(time.Unix(1, 0), earliestDeadline(time.Unix(1, 0), time.Unix(2, 0)))
+}
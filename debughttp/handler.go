@@ -0,0 +1,272 @@
+// Package debughttp implements http.Handler exposing live state of goconnpool pools.
+//
+// Handler renders the stats of the registered pools as HTML page (default) or as JSON
+// (use "format=json" query parameter or "Accept: application/json" header).
+// Borrowed connections are shown with their borrow stack traces if goconnpool.Config.LeakThreshold is set.
+//
+// POST request with "pool", "server" and "mode" form values changes the server mode
+// (see goconnpool.ConnPool.SetServerMode). Such requests should have RequestHeader set: browsers don't allow
+// cross-site pages to send requests with custom headers, so servers can't be marked down by CSRF attacks.
+// Handler doesn't authenticate requests: mount it behind the authentication of your application.
+//
+//	h := debughttp.NewHandler()
+//	h.Register("users", usersPool)
+//	http.Handle("/debug/goconnpool", h)
+package debughttp
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/derElektrobesen/goconnpool"
+)
+
+// RequestHeader is required (with any value) by the requests changing the server mode.
+const RequestHeader = "X-Goconnpool-Request"
+
+// Handler implements http.Handler interface.
+type Handler struct {
+	mu    sync.RWMutex
+	pools map[string]goconnpool.ConnPool
+
+	now func() time.Time
+}
+
+// NewHandler creates new handler without registered pools.
+func NewHandler() *Handler {
+	return &Handler{
+		pools: map[string]goconnpool.ConnPool{},
+		now:   time.Now,
+	}
+}
+
+// Register adds the pool to be shown by the handler.
+// Pool registered with the same name is replaced.
+func (h *Handler) Register(name string, p goconnpool.ConnPool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.pools[name] = p
+}
+
+// ServeHTTP implements http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.serveStats(w, r)
+	case http.MethodPost:
+		h.serveSetMode(w, r)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+type poolView struct {
+	Name    string       `json:"name"`
+	Servers []serverView `json:"servers"`
+}
+
+type serverView struct {
+	Address       string                `json:"address"`
+	Up            bool                  `json:"up"`
+	Mode          goconnpool.ServerMode `json:"mode"`
+	RetryIn       string                `json:"retry_in,omitempty"`
+	OpenConns     int                   `json:"open_conns"`
+	IdleConns     int                   `json:"idle_conns"`
+	InUseConns    int                   `json:"in_use_conns"`
+	Dials         uint64                `json:"dials"`
+	DialErrors    uint64                `json:"dial_errors"`
+	RatelimitHits uint64                `json:"ratelimit_hits"`
+	Borrowed      []borrowedView        `json:"borrowed"`
+}
+
+type borrowedView struct {
+	ID         uint64    `json:"id"`
+	BorrowedAt time.Time `json:"borrowed_at"`
+	Age        string    `json:"age"`
+	Stack      string    `json:"stack,omitempty"`
+}
+
+func (h *Handler) views() []poolView {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	now := h.now()
+	views := make([]poolView, 0, len(h.pools))
+
+	for name, p := range h.pools {
+		v := poolView{Name: name}
+		for _, s := range p.Stats().Servers {
+			v.Servers = append(v.Servers, newServerView(now, s))
+		}
+
+		views = append(views, v)
+	}
+
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].Name < views[j].Name
+	})
+
+	return views
+}
+
+func newServerView(now time.Time, s goconnpool.ServerStats) serverView {
+	v := serverView{
+		Address:       s.Address,
+		Up:            s.Up,
+		Mode:          s.Mode,
+		OpenConns:     s.OpenConns,
+		IdleConns:     s.IdleConns,
+		InUseConns:    s.InUseConns,
+		Dials:         s.Dials,
+		DialErrors:    s.DialErrors,
+		RatelimitHits: s.RatelimitHits,
+		Borrowed:      make([]borrowedView, 0, len(s.Borrowed)),
+	}
+
+	if !s.Up && s.NextRetry.After(now) {
+		v.RetryIn = s.NextRetry.Sub(now).String()
+	}
+
+	for _, b := range s.Borrowed {
+		v.Borrowed = append(v.Borrowed, borrowedView{
+			ID:         b.ID,
+			BorrowedAt: b.BorrowedAt,
+			Age:        now.Sub(b.BorrowedAt).String(),
+			Stack:      b.Stack,
+		})
+	}
+
+	return v
+}
+
+func wantJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "json" {
+		return true
+	}
+
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+func (h *Handler) serveStats(w http.ResponseWriter, r *http.Request) {
+	views := h.views()
+
+	if wantJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(map[string]interface{}{"pools": views}) // nolint:errcheck
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	statsTemplate.Execute(w, views) // nolint:errcheck
+}
+
+func (h *Handler) serveSetMode(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(RequestHeader) == "" {
+		http.Error(w, RequestHeader+" header is required", http.StatusForbidden)
+		return
+	}
+
+	var (
+		name = r.FormValue("pool")
+		addr = r.FormValue("server")
+		mode = goconnpool.ServerMode(r.FormValue("mode"))
+	)
+
+	h.mu.RLock()
+	p, ok := h.pools[name]
+	h.mu.RUnlock()
+
+	if !ok {
+		http.Error(w, "unknown pool "+name, http.StatusNotFound)
+		return
+	}
+
+	if err := p.SetServerMode(addr, mode); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if wantJSON(r) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+}
+
+var statsTemplate = template.Must(template.New("stats").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>goconnpool</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 2px 6px; text-align: left; vertical-align: top; }
+.down { background: #fdd; }
+</style>
+</head>
+<body>
+{{range $pool := .}}
+<h2>{{$pool.Name}}</h2>
+<table>
+<tr>
+<th>Server</th><th>Up</th><th>Mode</th><th>Retry in</th><th>Open</th><th>Idle</th><th>In use</th>
+<th>Dials</th><th>Dial errors</th><th>Ratelimit hits</th><th>Borrowed</th><th>Actions</th>
+</tr>
+{{range .Servers}}
+<tr{{if not .Up}} class="down"{{end}}>
+<td>{{.Address}}</td><td>{{.Up}}</td><td>{{.Mode}}</td><td>{{.RetryIn}}</td>
+<td>{{.OpenConns}}</td><td>{{.IdleConns}}</td><td>{{.InUseConns}}</td>
+<td>{{.Dials}}</td><td>{{.DialErrors}}</td><td>{{.RatelimitHits}}</td>
+<td>{{range .Borrowed}}
+{{if .Stack}}<details><summary>#{{.ID}} for {{.Age}}</summary><pre>{{.Stack}}</pre></details>
+{{else}}#{{.ID}} for {{.Age}}<br>
+{{end}}{{end}}</td>
+<td>
+<form method="post">
+<input type="hidden" name="pool" value="{{$pool.Name}}">
+<input type="hidden" name="server" value="{{.Address}}">
+<button name="mode" value="auto">Up</button>
+<button name="mode" value="down">Down</button>
+<button name="mode" value="drain">Drain</button>
+</form>
+</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p>No pools registered</p>
+{{end}}
+<script>
+document.addEventListener("submit", function(e) {
+	e.preventDefault();
+	var body = new URLSearchParams(new FormData(e.target));
+	if (e.submitter) {
+		body.set(e.submitter.name, e.submitter.value);
+	}
+
+	fetch(location.pathname, {
+		method: "POST",
+		headers: {"` + RequestHeader + `": "1", "Accept": "application/json"},
+		body: body
+	}).then(function(resp) {
+		if (!resp.ok) {
+			return resp.text().then(alert);
+		}
+
+		location.reload();
+	});
+});
+</script>
+</body>
+</html>
+`))
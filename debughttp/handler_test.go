@@ -0,0 +1,139 @@
+package debughttp
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/derElektrobesen/goconnpool"
+	"github.com/derElektrobesen/goconnpool/internal/pooltest"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPool(t *testing.T) goconnpool.ConnPool {
+	p := goconnpool.NewConnPool(goconnpool.Config{
+		MaxRPS: math.MaxInt32,
+		Dialer: pooltest.Dialer{},
+	})
+
+	pooltest.RegisterServers(t, p)
+
+	_, err := p.OpenConnNonBlock(context.Background()) // "ok" is dialed and borrowed
+	require.NoError(t, err)
+
+	_, err = p.OpenConnNonBlock(context.Background()) // "bad" is down
+	require.Error(t, err)
+
+	return p
+}
+
+func TestHandlerJSON(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	h := NewHandler()
+	h.Register("test", newTestPool(t))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?format=json", nil))
+	ass.Equal(http.StatusOK, rec.Code)
+	ass.Equal("application/json", rec.Header().Get("Content-Type"))
+
+	var resp struct {
+		Pools []poolView `json:"pools"`
+	}
+
+	ass.NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	ass.Len(resp.Pools, 1)
+	ass.Equal("test", resp.Pools[0].Name)
+
+	servers := resp.Pools[0].Servers
+	ass.Len(servers, 2)
+
+	ass.Equal("ok", servers[0].Address)
+	ass.True(servers[0].Up)
+	ass.Equal(goconnpool.ServerModeAuto, servers[0].Mode)
+	ass.Equal(1, servers[0].InUseConns)
+	ass.Len(servers[0].Borrowed, 1)
+
+	ass.Equal("bad", servers[1].Address)
+	ass.False(servers[1].Up)
+	ass.NotEmpty(servers[1].RetryIn)
+	ass.Equal(uint64(1), servers[1].DialErrors)
+	ass.Empty(servers[1].Borrowed)
+}
+
+func TestHandlerHTML(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	h := NewHandler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	ass.Contains(rec.Body.String(), "No pools registered")
+
+	h.Register("test", newTestPool(t))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	ass.Equal(http.StatusOK, rec.Code)
+	ass.Equal("text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	ass.Contains(rec.Body.String(), "<h2>test</h2>")
+	ass.Contains(rec.Body.String(), `<tr class="down">`)
+}
+
+func TestHandlerSetMode(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	p := newTestPool(t)
+	h := NewHandler()
+	h.Register("test", p)
+
+	post := func(pool, server, mode, accept string) *httptest.ResponseRecorder {
+		form := url.Values{"pool": {pool}, "server": {server}, "mode": {mode}}
+		req := httptest.NewRequest(http.MethodPost, "/debug", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", accept)
+		req.Header.Set(RequestHeader, "1")
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("test", "ok", "drain", "text/html")
+	ass.Equal(http.StatusSeeOther, rec.Code)
+	ass.Equal("/debug", rec.Header().Get("Location"))
+	ass.Equal(goconnpool.ServerModeDrain, p.Stats().Servers[0].Mode)
+
+	ass.False(p.Stats().Servers[0].Up)
+
+	rec = post("test", "bad", "auto", "application/json")
+	ass.Equal(http.StatusNoContent, rec.Code)
+	ass.True(p.Stats().Servers[1].Up)
+
+	// requests without RequestHeader could be sent by other sites
+	form := url.Values{"pool": {"test"}, "server": {"bad"}, "mode": {"down"}}
+	req := httptest.NewRequest(http.MethodPost, "/debug", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	ass.Equal(http.StatusForbidden, rec.Code)
+	ass.Equal(goconnpool.ServerModeAuto, p.Stats().Servers[1].Mode)
+
+	ass.Equal(http.StatusNotFound, post("xxx", "ok", "down", "").Code)
+	ass.Equal(http.StatusBadRequest, post("test", "xxx", "down", "").Code)
+	ass.Equal(http.StatusBadRequest, post("test", "ok", "xxx", "").Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/", nil))
+	ass.Equal(http.StatusMethodNotAllowed, rec.Code)
+}
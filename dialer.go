@@ -3,6 +3,10 @@ package goconnpool
 import (
 	"context"
 	"net"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
 )
 
 // Dialer interface used to dial specific server independently from the specific protocol.
@@ -21,13 +25,114 @@ type Dialer interface {
 	Dial(ctx context.Context, address string) (net.Conn, error)
 }
 
-// TCPDialer is the default implementation of Dialer interface.
+// AddressValidator could be implemented by Dialer (or Factory) to check the address during RegisterServer call.
+type AddressValidator interface {
+	ValidateAddress(address string) error
+}
+
+// TCPDialer is the basic implementation of Dialer interface (see DefaultDialer for the one used by default).
 // Use it for raw TCP connections.
+//
+// Address is dialed as "tcp" one unless URL-style address is used:
+//   - tcp://host:port, tcp4://host:port, tcp6://host:port
+//   - unix:///path/to/socket, unixpacket:///path/to/socket
+//
+// So one pool could mix local sockets and remote TCP servers.
 type TCPDialer struct {
 	d net.Dialer
 }
 
-// Dial dials some TCP server using net.Dialer structure.
+// Dial dials some TCP server (or unix socket) using net.Dialer structure.
 func (d *TCPDialer) Dial(ctx context.Context, address string) (net.Conn, error) {
-	return d.d.DialContext(ctx, "tcp", address)
+	network, address, err := parseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	network = boundNetwork(ctx, network)
+	return netDialer(ctx, network, &d.d).DialContext(ctx, network, address)
+}
+
+// ValidateAddress implements AddressValidator interface.
+func (d *TCPDialer) ValidateAddress(address string) error {
+	_, _, err := parseAddress(address)
+	return err
+}
+
+// parseAddress returns network and address to be passed into net.Dialer.
+func parseAddress(addr string) (string, string, error) {
+	if !strings.Contains(addr, "://") {
+		return "tcp", addr, nil
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", "", errors.Wrap(err, "invalid address")
+	}
+
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6":
+		if _, _, err := net.SplitHostPort(u.Host); err != nil {
+			return "", "", errors.Wrapf(err, "invalid address %q", addr)
+		}
+
+		if u.Path != "" && u.Path != "/" {
+			return "", "", errors.Errorf("invalid address %q: unexpected path", addr)
+		}
+
+		return u.Scheme, u.Host, nil
+	case "unix", "unixpacket":
+		if u.Host != "" || u.Path == "" {
+			return "", "", errors.Errorf("invalid address %q: %s:///path/to/socket is expected", addr, u.Scheme)
+		}
+
+		return u.Scheme, u.Path, nil
+	}
+
+	return "", "", errors.Errorf("invalid address %q: unsupported network %q", addr, u.Scheme)
+}
+
+// dialerFactory implements Factory of net.Conn using the Dialer.
+type dialerFactory struct {
+	d       Dialer
+	wrap    ConnWrapper
+	traffic *trafficRegistry
+}
+
+func (f dialerFactory) Create(ctx context.Context, addr string) (net.Conn, error) {
+	cn, err := f.d.Dial(ctx, addr)
+	if err != nil || f.wrap == nil {
+		return cn, err
+	}
+
+	info := ConnInfo{
+		ServerAddr: addr,
+		state:      &connState{},
+	}
+
+	if f.traffic != nil {
+		info.traffic = f.traffic.get(addr)
+	}
+
+	return &wrappedConn{
+		Conn:  f.wrap(cn, info),
+		orig:  cn,
+		state: info.state,
+	}, nil
+}
+
+func (f dialerFactory) Close(cn net.Conn) error {
+	return cn.Close()
+}
+
+func (f dialerFactory) Validate(net.Conn) error {
+	return nil
+}
+
+func (f dialerFactory) ValidateAddress(addr string) error {
+	if v, ok := f.d.(AddressValidator); ok {
+		return v.ValidateAddress(addr)
+	}
+
+	return nil
 }
@@ -1,5 +1,10 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: dialer.go
+// Source: github.com/derElektrobesen/goconnpool (interfaces: Dialer)
+//
+// Generated by this command:
+//
+//	mockgen -package=goconnpool -self_package=github.com/derElektrobesen/goconnpool -destination=dialer_mock_test.go github.com/derElektrobesen/goconnpool Dialer
+//
 
 // Package goconnpool is a generated GoMock package.
 package goconnpool
@@ -9,41 +14,44 @@ import (
 	net "net"
 	reflect "reflect"
 
-	gomock "github.com/golang/mock/gomock"
+	gomock "go.uber.org/mock/gomock"
 )
 
-// MockDialer is a mock of Dialer interface
+// MockDialer is a mock of Dialer interface.
 type MockDialer struct {
 	ctrl     *gomock.Controller
 	recorder *MockDialerMockRecorder
+	isgomock struct{}
 }
 
-// MockDialerMockRecorder is the mock recorder for MockDialer
+// MockDialerMockRecorder is the mock recorder for MockDialer.
 type MockDialerMockRecorder struct {
 	mock *MockDialer
 }
 
-// NewMockDialer creates a new mock instance
+// NewMockDialer creates a new mock instance.
 func NewMockDialer(ctrl *gomock.Controller) *MockDialer {
 	mock := &MockDialer{ctrl: ctrl}
 	mock.recorder = &MockDialerMockRecorder{mock}
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
+// EXPECT returns an object that allows the caller to indicate expected use.
 func (m *MockDialer) EXPECT() *MockDialerMockRecorder {
 	return m.recorder
 }
 
-// Dial mocks base method
+// Dial mocks base method.
 func (m *MockDialer) Dial(ctx context.Context, address string) (net.Conn, error) {
+	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Dial", ctx, address)
 	ret0, _ := ret[0].(net.Conn)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Dial indicates an expected call of Dial
-func (mr *MockDialerMockRecorder) Dial(ctx, address interface{}) *gomock.Call {
+// Dial indicates an expected call of Dial.
+func (mr *MockDialerMockRecorder) Dial(ctx, address any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Dial", reflect.TypeOf((*MockDialer)(nil).Dial), ctx, address)
 }
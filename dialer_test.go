@@ -0,0 +1,79 @@
+package goconnpool
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddress(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		addr    string
+		network string
+		address string
+		err     bool
+	}{
+		{addr: "127.0.0.1:80", network: "tcp", address: "127.0.0.1:80"},
+		{addr: "google.com", network: "tcp", address: "google.com"},
+		{addr: "tcp://127.0.0.1:80", network: "tcp", address: "127.0.0.1:80"},
+		{addr: "tcp4://127.0.0.1:80/", network: "tcp4", address: "127.0.0.1:80"},
+		{addr: "tcp6://[::1]:80", network: "tcp6", address: "[::1]:80"},
+		{addr: "unix:///var/run/app.sock", network: "unix", address: "/var/run/app.sock"},
+		{addr: "unixpacket:///tmp/app.sock", network: "unixpacket", address: "/tmp/app.sock"},
+		{addr: "tcp://127.0.0.1", err: true},
+		{addr: "tcp://127.0.0.1:80/path", err: true},
+		{addr: "unix://app.sock", err: true},
+		{addr: "unix://", err: true},
+		{addr: "udp://127.0.0.1:80", err: true},
+		{addr: "tcp://%zz", err: true},
+	} {
+		network, address, err := parseAddress(tc.addr)
+		if tc.err {
+			require.Error(t, err, tc.addr)
+			continue
+		}
+
+		require.NoError(t, err, tc.addr)
+		require.Equal(t, tc.network, network, tc.addr)
+		require.Equal(t, tc.address, address, tc.addr)
+	}
+}
+
+func TestUnixSocketDial(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "app.sock")
+	l, err := net.Listen("unix", path)
+	ass.NoError(err)
+	defer l.Close()
+	defer os.Remove(path)
+
+	go func() {
+		cn, err := l.Accept()
+		if err == nil {
+			cn.Write([]byte("ok")) // nolint:errcheck
+			cn.Close()             // nolint:errcheck
+		}
+	}()
+
+	p := NewConnPool(Config{})
+	ass.Error(p.RegisterServer("unix://app.sock"))
+	ass.NoError(p.RegisterServer("unix://" + path))
+	ass.Len(p.Stats().Servers, 1)
+
+	cn, err := p.OpenConn(context.Background())
+	ass.NoError(err)
+	defer cn.Close()
+
+	buf := make([]byte, 2)
+	_, err = cn.Read(buf)
+	ass.NoError(err)
+	ass.Equal("ok", string(buf))
+}
@@ -0,0 +1,86 @@
+package goconnpool
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// ErrorClass tells ConnPool.Do what to do with the connection after the callback returned an error.
+type ErrorClass int
+
+const (
+	// ErrorClassConnBroken means the connection can't be reused: it is closed.
+	// This is the default class of any error.
+	ErrorClassConnBroken ErrorClass = iota
+
+	// ErrorClassRequest means the error isn't related to the connection (e.g. the server returned
+	// some application-level error): the connection is returned into pool.
+	ErrorClassRequest
+
+	// ErrorClassServerDown means the server can't process requests: the connection is closed,
+	// the server is marked down (as if dial was failed) and its idle connections are closed.
+	// The server is marked up on the next successful dial or ConnPool.Do request.
+	ErrorClassServerDown
+)
+
+// ErrorClassifier classifies errors returned by the ConnPool.Do callback.
+type ErrorClassifier func(err error) ErrorClass
+
+func (p *pool[T]) Do(ctx context.Context, fn func(T) error) error {
+	return p.doWith(ctx, func(r *resource[T]) error {
+		return fn(r.value)
+	})
+}
+
+func (p *pool[T]) doWith(ctx context.Context, fn func(*resource[T]) error) error {
+	r, err := p.openConnBlock(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	return p.do(r, fn)
+}
+
+// do runs fn using the connection passed and releases the connection according to the result.
+// Connection is closed if fn panics: its state is unknown.
+func (p *pool[T]) do(r *resource[T], fn func(*resource[T]) error) error {
+	defer func() {
+		if v := recover(); v != nil {
+			r.Close() // nolint:errcheck
+			panic(v)
+		}
+	}()
+
+	return p.release(r, fn(r))
+}
+
+// release releases the connection according to the error returned by the request.
+// Error passed is returned as is.
+func (p *pool[T]) release(r *resource[T], err error) error {
+	if err == nil {
+		p.budget.deposit()
+		r.reportSuccess()
+		return errors.Wrap(r.ReturnToPool(), "can't return connection into pool")
+	}
+
+	switch p.classify(err) {
+	case ErrorClassRequest:
+		r.ReturnToPool() // nolint:errcheck
+	case ErrorClassServerDown:
+		r.Close() // nolint:errcheck
+		r.reportFailure(err)
+	default:
+		r.Close() // nolint:errcheck
+	}
+
+	return err
+}
+
+func (p *pool[T]) classify(err error) ErrorClass {
+	if p.cfg.ErrorClassifier == nil {
+		return ErrorClassConnBroken
+	}
+
+	return p.cfg.ErrorClassifier(err)
+}
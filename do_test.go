@@ -0,0 +1,148 @@
+package goconnpool
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+var (
+	errTestRequest    = fmt.Errorf("bad request")
+	errTestServerDown = fmt.Errorf("server unavailable")
+	errTestBroken     = fmt.Errorf("broken pipe")
+)
+
+func testErrorClassifier(err error) ErrorClass {
+	switch err {
+	case errTestRequest:
+		return ErrorClassRequest
+	case errTestServerDown:
+		return ErrorClassServerDown
+	default:
+		return ErrorClassConnBroken
+	}
+}
+
+func newTestClosableConn(ctrl *gomock.Controller, needClose bool) net.Conn {
+	cl := NewMockcloser(ctrl)
+	if needClose {
+		cl.EXPECT().Close()
+	}
+
+	return &closableTestConn{
+		Conn:   &net.IPConn{},
+		closer: cl,
+	}
+}
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dialer := NewMockDialer(ctrl)
+	p := newConnPool(Config{
+		MaxRPS:                 math.MaxInt32,
+		MaxConnsPerServer:      2,
+		InitialBackoffInterval: time.Hour,
+		MaxBackoffInterval:     time.Hour,
+		Dialer:                 dialer,
+		Logger:                 testLogger{t: t},
+		ErrorClassifier:        testErrorClassifier,
+	})
+
+	err := p.Do(context.Background(), func(Conn) error { return nil })
+	ass.Equal(ErrNoServersRegistered, err)
+
+	ass.NoError(p.RegisterServer("y"))
+
+	gomock.InOrder(
+		dialer.EXPECT().Dial(gomock.Any(), "y").Return(newTestClosableConn(ctrl, true), nil),
+		dialer.EXPECT().Dial(gomock.Any(), "y").Return(newTestClosableConn(ctrl, true), nil),
+		dialer.EXPECT().Dial(gomock.Any(), "y").Return(newTestClosableConn(ctrl, true), nil),
+	)
+
+	// success: connection is returned into pool
+	ass.NoError(p.Do(context.Background(), func(cn Conn) error {
+		ass.Equal("y", cn.ServerAddr())
+		return nil
+	}))
+	ass.Equal(1, p.Stats().Servers[0].IdleConns)
+
+	// request error: connection is returned into pool
+	ass.Equal(errTestRequest, p.Do(context.Background(), func(Conn) error { return errTestRequest }))
+	ass.Equal(1, p.Stats().Servers[0].IdleConns)
+
+	// unknown error: connection is closed
+	ass.Equal(errTestBroken, p.Do(context.Background(), func(Conn) error { return errTestBroken }))
+	ass.Equal(0, p.Stats().Servers[0].OpenConns)
+
+	// server error: connection is closed, idle connections are closed, server is down
+	ass.NoError(p.Do(context.Background(), func(Conn) error {
+		return p.Do(context.Background(), func(Conn) error { return nil }) // second connection is dialed
+	}))
+	ass.Equal(2, p.Stats().Servers[0].IdleConns)
+
+	ass.Equal(errTestServerDown, p.Do(context.Background(), func(Conn) error { return errTestServerDown }))
+
+	st := p.Stats().Servers[0]
+	ass.False(st.Up)
+	ass.Equal(0, st.OpenConns)
+
+	// server isn't dialed until backoff interval passed
+	_, err = p.OpenConnNonBlock(context.Background())
+	ass.Error(err)
+}
+
+func TestDoServerRecoveryAndPanics(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dialer := NewMockDialer(ctrl)
+	p := newConnPool(Config{
+		MaxRPS:                 math.MaxInt32,
+		MaxConnsPerServer:      2,
+		InitialBackoffInterval: time.Hour,
+		MaxBackoffInterval:     time.Hour,
+		Dialer:                 dialer,
+		Logger:                 testLogger{t: t},
+		ErrorClassifier:        testErrorClassifier,
+	})
+
+	ass.NoError(p.RegisterServer("y"))
+
+	for i := 0; i < 3; i++ {
+		dialer.EXPECT().Dial(gomock.Any(), "y").Return(newTestClosableConn(ctrl, true), nil)
+	}
+
+	// request sent into the other connection succeeds: server is up again
+	ass.NoError(p.Do(context.Background(), func(Conn) error {
+		ass.Equal(errTestServerDown, p.Do(context.Background(), func(Conn) error { return errTestServerDown }))
+		ass.False(p.Stats().Servers[0].Up)
+		return nil
+	}))
+
+	st := p.Stats().Servers[0]
+	ass.True(st.Up)
+	ass.Equal(1, st.IdleConns)
+
+	// callback panics: connection is closed, panic is propagated
+	ass.PanicsWithValue("oops", func() {
+		p.Do(context.Background(), func(Conn) error { panic("oops") }) // nolint:errcheck
+	})
+	ass.Equal(0, p.Stats().Servers[0].OpenConns)
+
+	// slot is released: new connection could be opened
+	ass.Equal(errTestBroken, p.Do(context.Background(), func(Conn) error { return errTestBroken }))
+}
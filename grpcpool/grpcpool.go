@@ -0,0 +1,198 @@
+// Package grpcpool integrates goconnpool.ConnPool with gRPC clients.
+//
+// NewBuilder exposes the registered servers of the pool as gRPC resolver: servers which are down (or disabled
+// using SetServerMode) aren't resolved. DialContext dials servers through the pool: ratelimits and backoff of the
+// pool are applied to gRPC connections, so gRPC clients follow the same server health as raw TCP pools do.
+//
+//	cn, err := grpc.Dial(grpcpool.Scheme+":///users",
+//		grpc.WithResolvers(grpcpool.NewBuilder(pool)),
+//		grpc.WithContextDialer(grpcpool.DialContext(pool)),
+//		grpc.WithInsecure())
+package grpcpool
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/derElektrobesen/goconnpool"
+	"google.golang.org/grpc/resolver"
+)
+
+const (
+	// Scheme is the default scheme of the resolver.
+	Scheme = "goconnpool"
+
+	// DefaultRefreshInterval is the default interval of the servers state polling.
+	DefaultRefreshInterval = time.Second
+)
+
+// Option configures the resolver builder.
+type Option func(*builder)
+
+// WithScheme sets the scheme of the resolver.
+// Scheme is used by default.
+func WithScheme(scheme string) Option {
+	return func(b *builder) {
+		b.scheme = scheme
+	}
+}
+
+// WithClock sets the clock used to poll the servers state: pass Config.Clock of the pool.
+// goconnpool.SystemClock is used by default.
+func WithClock(c goconnpool.Clock) Option {
+	return func(b *builder) {
+		b.clock = c
+	}
+}
+
+// WithRefreshInterval sets the interval of the servers state polling.
+// DefaultRefreshInterval is used by default.
+func WithRefreshInterval(interval time.Duration) Option {
+	return func(b *builder) {
+		b.interval = interval
+	}
+}
+
+type builder struct {
+	pool     goconnpool.ConnPool
+	scheme   string
+	interval time.Duration
+	clock    goconnpool.Clock
+}
+
+// NewBuilder creates gRPC resolver builder which resolves any target into the servers of the pool passed.
+//
+// Only servers which are up (in auto mode) are resolved. If there is no such server, all registered servers are
+// resolved: DialContext will fail until some server is up.
+func NewBuilder(p goconnpool.ConnPool, opts ...Option) resolver.Builder {
+	b := &builder{
+		pool:     p,
+		scheme:   Scheme,
+		interval: DefaultRefreshInterval,
+		clock:    goconnpool.SystemClock{},
+	}
+
+	for _, o := range opts {
+		o(b)
+	}
+
+	return b
+}
+
+func (b *builder) Build(_ resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	r := &poolResolver{
+		pool:       b.pool,
+		cc:         cc,
+		interval:   b.interval,
+		clock:      b.clock,
+		resolveNow: make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+
+	r.update()
+	go r.watch()
+
+	return r, nil
+}
+
+func (b *builder) Scheme() string {
+	return b.scheme
+}
+
+type poolResolver struct {
+	pool     goconnpool.ConnPool
+	cc       resolver.ClientConn
+	interval time.Duration
+	clock    goconnpool.Clock
+
+	last []string
+
+	resolveNow chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+func (r *poolResolver) watch() {
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-r.clock.After(r.interval):
+		case <-r.resolveNow:
+		}
+
+		r.update()
+	}
+}
+
+func (r *poolResolver) update() {
+	var all, alive []string
+	for _, s := range r.pool.Stats().Servers {
+		all = append(all, s.Address)
+		if s.Up {
+			alive = append(alive, s.Address)
+		}
+	}
+
+	if len(alive) == 0 {
+		alive = all
+	}
+
+	if equal(alive, r.last) {
+		return
+	}
+
+	r.last = alive
+
+	addrs := make([]resolver.Address, 0, len(alive))
+	for _, addr := range alive {
+		addrs = append(addrs, resolver.Address{Addr: addr})
+	}
+
+	r.cc.UpdateState(resolver.State{Addresses: addrs}) // nolint:errcheck
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *poolResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolveNow <- struct{}{}:
+	default:
+	}
+}
+
+func (r *poolResolver) Close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+	})
+}
+
+// DialContext returns the dial function which could be used with grpc.WithContextDialer.
+//
+// Connections are opened using ConnPool.OpenConnToServerBlock: ratelimits of the server are waited for within ctx,
+// dial fails immediately if the server is down (gRPC applies its own backoff then).
+// Connection is owned by gRPC until it is closed: use Config.MaxConnsPerServer and Config.LeakThreshold carefully.
+func DialContext(p goconnpool.ConnPool) func(ctx context.Context, addr string) (net.Conn, error) {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		cn, err := p.OpenConnToServerBlock(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return cn, nil
+	}
+}
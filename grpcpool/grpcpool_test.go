@@ -0,0 +1,168 @@
+package grpcpool
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/derElektrobesen/goconnpool"
+	"github.com/derElektrobesen/goconnpool/internal/pooltest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/resolver"
+)
+
+type testClientConn struct {
+	resolver.ClientConn
+
+	states chan []string
+}
+
+func (cc *testClientConn) UpdateState(st resolver.State) error {
+	var addrs []string
+	for _, a := range st.Addresses {
+		addrs = append(addrs, a.Addr)
+	}
+
+	cc.states <- addrs
+	return nil
+}
+
+func newTestPool(t *testing.T) goconnpool.ConnPool {
+	p := goconnpool.NewConnPool(goconnpool.Config{
+		MaxRPS:                 math.MaxInt32,
+		InitialBackoffInterval: time.Hour,
+		MaxBackoffInterval:     time.Hour,
+		Dialer:                 pooltest.Dialer{},
+	})
+
+	pooltest.RegisterServers(t, p)
+
+	return p
+}
+
+func TestResolver(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	p := newTestPool(t)
+
+	b := NewBuilder(p, WithScheme("test"), WithRefreshInterval(time.Hour))
+	ass.Equal("test", b.Scheme())
+
+	cc := &testClientConn{states: make(chan []string, 10)}
+	r, err := b.Build(resolver.Target{}, cc, resolver.BuildOptions{})
+	ass.NoError(err)
+	defer r.Close()
+
+	ass.Equal([]string{"ok", "bad"}, <-cc.states)
+
+	// "bad" is marked down
+	_, err = DialContext(p)(context.Background(), "bad")
+	ass.Error(err)
+
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	ass.Equal([]string{"ok"}, <-cc.states)
+
+	// all servers are down: all servers are resolved
+	ass.NoError(p.SetServerMode("ok", goconnpool.ServerModeDown))
+
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	ass.Equal([]string{"ok", "bad"}, <-cc.states)
+
+	// state isn't changed: no updates expected
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	select {
+	case st := <-cc.states:
+		ass.Fail("unexpected update", "%v", st)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDialContext(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	p := newTestPool(t)
+	dial := DialContext(p)
+
+	cn, err := dial(context.Background(), "ok")
+	ass.NoError(err)
+	ass.Equal(1, p.Stats().Servers[0].InUseConns)
+
+	ass.NoError(cn.Close())
+	ass.Equal(0, p.Stats().Servers[0].OpenConns)
+
+	_, err = dial(context.Background(), "unknown")
+	ass.Equal(goconnpool.ErrUnknownServer, errors.Cause(err))
+}
+
+func TestDialContextRatelimited(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	p := goconnpool.NewConnPool(goconnpool.Config{
+		MaxRPS:            math.MaxInt32,
+		MaxConnsPerServer: 1,
+		Dialer:            pooltest.Dialer{},
+	})
+	pooltest.RegisterServers(t, p, pooltest.OK)
+
+	dial := DialContext(p)
+
+	cn, err := dial(context.Background(), pooltest.OK)
+	ass.NoError(err)
+
+	// the only connection is in use: dial waits for it within the context
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = dial(ctx, pooltest.OK)
+	ass.EqualError(err, "operation cancelled")
+
+	done := make(chan error, 1)
+	go func() {
+		cn, err := dial(context.Background(), pooltest.OK)
+		if err == nil {
+			err = cn.Close()
+		}
+		done <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	ass.NoError(cn.Close())
+	ass.NoError(<-done)
+}
+
+func TestResolverClock(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	p := newTestPool(t)
+	cl := clock.NewMock()
+
+	b := NewBuilder(p, WithRefreshInterval(time.Minute), WithClock(cl))
+
+	cc := &testClientConn{states: make(chan []string, 10)}
+	r, err := b.Build(resolver.Target{}, cc, resolver.BuildOptions{})
+	ass.NoError(err)
+	defer r.Close()
+
+	ass.Equal([]string{"ok", "bad"}, <-cc.states)
+
+	ass.NoError(p.SetServerMode("bad", goconnpool.ServerModeDown))
+
+	// servers state is polled on the clock passed only
+	for {
+		cl.Add(time.Minute)
+
+		select {
+		case st := <-cc.states:
+			ass.Equal([]string{"ok"}, st)
+			return
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
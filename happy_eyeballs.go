@@ -0,0 +1,393 @@
+package goconnpool
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultResolveInterval is the default value for HappyEyeballsDialer.ResolveInterval.
+	DefaultResolveInterval = 30 * time.Second
+
+	// DefaultAttemptDelay is the default value for HappyEyeballsDialer.AttemptDelay (see RFC 8305).
+	DefaultAttemptDelay = 250 * time.Millisecond
+)
+
+// Resolver interface is used to resolve hostnames. *net.Resolver implements it.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// HappyEyeballsDialer is the implementation of Dialer interface for hostnames resolving to several IPs.
+//
+// Each IP of the hostname is the sub-server with its own backoff: the registered server is marked down only if
+// all its IPs are failed. IPs are dialed using RFC 8305 Happy Eyeballs algorithm: IPv6 and IPv4 addresses
+// are interleaved, next IP is dialed if the previous one hasn't connected within AttemptDelay (or failed),
+// the first established connection wins. IPs which are in backoff are skipped unless all IPs are in backoff.
+//
+// Hostnames are re-resolved every ResolveInterval: the last resolved IPs are used if resolution fails.
+// State of hostnames which aren't dialed for ResolveInterval (and have no IPs in backoff) is dropped.
+// Addresses format is the same TCPDialer has; IP addresses and unix sockets are dialed as is.
+//
+// HappyEyeballsDialer is the default Config.Dialer. Its scope is the Dial call only: IPs state is kept
+// by the dialer and isn't exposed in Stats; the pool sees one server per registered hostname, which is up while
+// any of its IPs is connectable. TLSDialer and ProxyDialer use TCPDialer unless another base dialer is passed.
+type HappyEyeballsDialer struct {
+	// Resolver is used to resolve hostnames.
+	// Default is net.DefaultResolver.
+	Resolver Resolver
+
+	// ResolveInterval is the interval of hostnames re-resolution.
+	// Default is DefaultResolveInterval.
+	ResolveInterval time.Duration
+
+	// AttemptDelay is the time to wait for the connection before the next IP is dialed.
+	// Default is DefaultAttemptDelay.
+	AttemptDelay time.Duration
+
+	// InitialBackoffInterval and MaxBackoffInterval configure ExponentialBackOff algorithm used for failed IPs.
+	// Defaults are DefaultInitBackoffInterval and DefaultMaxBackoffInterval.
+	InitialBackoffInterval time.Duration
+	MaxBackoffInterval     time.Duration
+
+	// Clock could be used to reimplement behaviour of system clock.
+	// Default is SystemClock.
+	Clock Clock
+
+	d    net.Dialer
+	dial func(ctx context.Context, network, address string) (net.Conn, error) // required for tests
+
+	once  sync.Once
+	mu    sync.Mutex
+	hosts map[string]*resolvedHost
+}
+
+type resolvedHost struct {
+	ips        []*ipState
+	resolvedAt time.Time
+	usedAt     time.Time
+}
+
+// ipState is the state of one IP of the hostname.
+type ipState struct {
+	addr      string
+	bOff      backoff.BackOff
+	downUntil time.Time
+}
+
+func (d *HappyEyeballsDialer) init() {
+	if d.Resolver == nil {
+		d.Resolver = net.DefaultResolver
+	}
+
+	if d.ResolveInterval == 0 {
+		d.ResolveInterval = DefaultResolveInterval
+	}
+
+	if d.AttemptDelay == 0 {
+		d.AttemptDelay = DefaultAttemptDelay
+	}
+
+	if d.InitialBackoffInterval == 0 {
+		d.InitialBackoffInterval = DefaultInitBackoffInterval
+	}
+
+	if d.MaxBackoffInterval == 0 {
+		d.MaxBackoffInterval = DefaultMaxBackoffInterval
+	}
+
+	if d.Clock == nil {
+		d.Clock = SystemClock{}
+	}
+
+	if d.dial == nil {
+		d.dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+			return netDialer(ctx, network, &d.d).DialContext(ctx, network, address)
+		}
+	}
+
+	d.hosts = map[string]*resolvedHost{}
+}
+
+// Dial dials the address passed racing all IPs of the hostname.
+func (d *HappyEyeballsDialer) Dial(ctx context.Context, address string) (net.Conn, error) {
+	d.once.Do(d.init)
+
+	network, address, err := parseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	// IPs of other family than the local address bound has (see WithLocalAddr) aren't dialed
+	network = boundNetwork(ctx, network)
+
+	if strings.HasPrefix(network, "unix") {
+		return d.dial(ctx, network, address)
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if net.ParseIP(host) != nil {
+		return d.dial(ctx, network, address)
+	}
+
+	ips, err := d.resolve(ctx, network, host, port)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.race(ctx, network, d.available(ips))
+}
+
+// ValidateAddress implements AddressValidator interface.
+func (d *HappyEyeballsDialer) ValidateAddress(address string) error {
+	_, _, err := parseAddress(address)
+	return err
+}
+
+func (d *HappyEyeballsDialer) resolve(ctx context.Context, network, host, port string) ([]*ipState, error) {
+	key := network + "://" + net.JoinHostPort(host, port)
+
+	d.mu.Lock()
+	h := d.hosts[key]
+	if h != nil {
+		h.usedAt = d.Clock.Now()
+	}
+	d.mu.Unlock()
+
+	if h != nil && d.Clock.Since(h.resolvedAt) < d.ResolveInterval {
+		return h.ips, nil
+	}
+
+	addrs, err := d.Resolver.LookupIPAddr(ctx, host)
+	if err == nil {
+		addrs = interleaveAddrs(network, addrs)
+		if len(addrs) == 0 {
+			err = errors.Errorf("no %s addresses found for %s", network, host)
+		}
+	}
+
+	if err != nil {
+		if h != nil {
+			// the last resolved IPs are used until resolution is succeeded
+			return h.ips, nil
+		}
+
+		return nil, errors.Wrapf(err, "can't resolve %s", host)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	prev := map[string]*ipState{}
+	if h := d.hosts[key]; h != nil {
+		for _, ip := range h.ips {
+			prev[ip.addr] = ip
+		}
+	}
+
+	h = &resolvedHost{
+		resolvedAt: d.Clock.Now(),
+		usedAt:     d.Clock.Now(),
+	}
+
+	for _, a := range addrs {
+		addr := net.JoinHostPort(a.String(), port)
+		ip, ok := prev[addr]
+		if !ok {
+			ip = &ipState{
+				addr: addr,
+				bOff: d.newBackOff(),
+			}
+		}
+
+		h.ips = append(h.ips, ip)
+	}
+
+	// the hostname dialed is stored after pruning: it is never dropped here
+	d.prune()
+	d.hosts[key] = h
+
+	return h.ips, nil
+}
+
+// prune drops hostnames which weren't dialed for ResolveInterval: hostnames with IPs in backoff are kept.
+func (d *HappyEyeballsDialer) prune() {
+	// XXX: Function should be called under mutex
+
+	now := d.Clock.Now()
+
+	for key, h := range d.hosts {
+		if now.Sub(h.usedAt) < d.ResolveInterval {
+			continue
+		}
+
+		inBackoff := false
+		for _, ip := range h.ips {
+			if now.Before(ip.downUntil) {
+				inBackoff = true
+				break
+			}
+		}
+
+		if !inBackoff {
+			delete(d.hosts, key)
+		}
+	}
+}
+
+func (d *HappyEyeballsDialer) newBackOff() backoff.BackOff {
+	bc := backoff.NewExponentialBackOff()
+	bc.InitialInterval = d.InitialBackoffInterval
+	bc.MaxInterval = d.MaxBackoffInterval
+	bc.MaxElapsedTime = 0
+	bc.Clock = d.Clock
+
+	bc.Reset() // required to re-setup config options
+	return bc
+}
+
+// available returns IPs which aren't in backoff (or all IPs if all of them are in backoff).
+func (d *HappyEyeballsDialer) available(ips []*ipState) []*ipState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.Clock.Now()
+
+	var res []*ipState
+	for _, ip := range ips {
+		if !now.Before(ip.downUntil) {
+			res = append(res, ip)
+		}
+	}
+
+	if len(res) == 0 {
+		return ips
+	}
+
+	return res
+}
+
+func (d *HappyEyeballsDialer) markDown(ip *ipState) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ip.downUntil = d.Clock.Now().Add(ip.bOff.NextBackOff())
+}
+
+func (d *HappyEyeballsDialer) markUp(ip *ipState) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ip.downUntil = time.Time{}
+	ip.bOff.Reset()
+}
+
+// race dials IPs passed using Happy Eyeballs algorithm.
+func (d *HappyEyeballsDialer) race(ctx context.Context, network string, ips []*ipState) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		ip  *ipState
+		cn  net.Conn
+		err error
+	}
+
+	results := make(chan result, len(ips))
+
+	var started, finished int
+	startNext := func() {
+		ip := ips[started]
+		started++
+
+		go func() {
+			cn, err := d.dial(ctx, network, ip.addr)
+			results <- result{ip: ip, cn: cn, err: err}
+		}()
+	}
+
+	// closePending closes connections established after the race was finished
+	closePending := func() {
+		for n := started - finished; n > 0; n-- {
+			if r := <-results; r.err == nil {
+				r.cn.Close() // nolint:errcheck
+			}
+		}
+	}
+
+	startNext()
+
+	var lastErr error
+	for {
+		var next <-chan time.Time
+		if started < len(ips) {
+			next = d.Clock.After(d.AttemptDelay)
+		}
+
+		select {
+		case <-next:
+			startNext()
+		case r := <-results:
+			finished++
+			if r.err == nil {
+				d.markUp(r.ip)
+				go closePending()
+				return r.cn, nil
+			}
+
+			lastErr = r.err
+
+			if ctx.Err() == nil {
+				d.markDown(r.ip)
+			}
+
+			if finished == len(ips) {
+				return nil, lastErr
+			}
+
+			if started < len(ips) {
+				startNext() // don't wait for AttemptDelay: the previous attempt is failed
+			}
+		case <-ctx.Done():
+			go closePending()
+			return nil, errors.WithStack(ctx.Err())
+		}
+	}
+}
+
+// interleaveAddrs filters addresses by network and interleaves address families (IPv6 first, see RFC 8305).
+func interleaveAddrs(network string, addrs []net.IPAddr) []net.IPAddr {
+	var v6, v4 []net.IPAddr
+	for _, a := range addrs {
+		if a.IP.To4() != nil {
+			if network != "tcp6" {
+				v4 = append(v4, a)
+			}
+		} else if network != "tcp4" {
+			v6 = append(v6, a)
+		}
+	}
+
+	res := make([]net.IPAddr, 0, len(v6)+len(v4))
+	for i := 0; i < len(v6) || i < len(v4); i++ {
+		if i < len(v6) {
+			res = append(res, v6[i])
+		}
+
+		if i < len(v4) {
+			res = append(res, v4[i])
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,226 @@
+package goconnpool
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+)
+
+type testResolver struct {
+	mu    sync.Mutex
+	addrs []net.IPAddr
+	err   error
+	calls int
+}
+
+func (r *testResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls++
+	return r.addrs, r.err
+}
+
+func ipAddrs(ips ...string) []net.IPAddr {
+	var res []net.IPAddr
+	for _, ip := range ips {
+		res = append(res, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+
+	return res
+}
+
+// testIPDialer emulates IPs behaviour: "fail" IPs are failed immediately, "hang" IPs wait for the context,
+// other IPs are connected.
+type testIPDialer struct {
+	mu     sync.Mutex
+	fail   map[string]bool
+	hang   map[string]bool
+	dialed []string
+}
+
+func (d *testIPDialer) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	d.mu.Lock()
+	d.dialed = append(d.dialed, address)
+	fail, hang := d.fail[address], d.hang[address]
+	d.mu.Unlock()
+
+	switch {
+	case fail:
+		return nil, fmt.Errorf("connection refused")
+	case hang:
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	cn, _ := net.Pipe()
+	return cn, nil
+}
+
+func (d *testIPDialer) getDialed() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	res := d.dialed
+	d.dialed = nil
+	return res
+}
+
+func TestInterleaveAddrs(t *testing.T) {
+	t.Parallel()
+
+	addrs := ipAddrs("192.0.2.1", "192.0.2.2", "192.0.2.3", "2001:db8::1", "2001:db8::2")
+
+	require.Equal(t, ipAddrs("2001:db8::1", "192.0.2.1", "2001:db8::2", "192.0.2.2", "192.0.2.3"),
+		interleaveAddrs("tcp", addrs))
+	require.Equal(t, ipAddrs("192.0.2.1", "192.0.2.2", "192.0.2.3"), interleaveAddrs("tcp4", addrs))
+	require.Equal(t, ipAddrs("2001:db8::1", "2001:db8::2"), interleaveAddrs("tcp6", addrs))
+}
+
+func TestHappyEyeballsDialer(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	r := &testResolver{addrs: ipAddrs("192.0.2.1", "192.0.2.2", "2001:db8::1")}
+	ipd := &testIPDialer{
+		fail: map[string]bool{"[2001:db8::1]:80": true},
+		hang: map[string]bool{"192.0.2.1:80": true},
+	}
+
+	d := &HappyEyeballsDialer{
+		Resolver:               r,
+		AttemptDelay:           50 * time.Millisecond,
+		InitialBackoffInterval: time.Hour,
+		dial:                   ipd.dial,
+	}
+
+	// IPv6 is failed: next IP is dialed immediately; it hangs: the last IP is dialed after AttemptDelay
+	_, err := d.Dial(context.Background(), "example.com:80")
+	ass.NoError(err)
+	ass.Equal([]string{"[2001:db8::1]:80", "192.0.2.1:80", "192.0.2.2:80"}, ipd.getDialed())
+
+	// failed IP is in backoff: it is skipped
+	_, err = d.Dial(context.Background(), "example.com:80")
+	ass.NoError(err)
+	ass.Equal([]string{"192.0.2.1:80", "192.0.2.2:80"}, ipd.getDialed())
+	ass.Equal(1, r.calls) // resolved IPs are cached
+
+	// IP addresses are dialed as is
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = d.Dial(ctx, "tcp4://192.0.2.1:80")
+	ass.Error(err)
+	ass.Equal([]string{"192.0.2.1:80"}, ipd.getDialed())
+
+	// all IPs are failed
+	ipd.mu.Lock()
+	ipd.fail["192.0.2.1:80"] = true
+	ipd.fail["192.0.2.2:80"] = true
+	ipd.mu.Unlock()
+
+	_, err = d.Dial(context.Background(), "example.com:80")
+	ass.Error(err)
+	ass.Len(ipd.getDialed(), 2)
+
+	// all IPs are in backoff: all of them are dialed
+	_, err = d.Dial(context.Background(), "example.com:80")
+	ass.Error(err)
+	ass.Len(ipd.getDialed(), 3)
+}
+
+func TestHappyEyeballsDialerReResolve(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	r := &testResolver{addrs: ipAddrs("192.0.2.1")}
+	ipd := &testIPDialer{}
+
+	d := &HappyEyeballsDialer{
+		Resolver:        r,
+		ResolveInterval: time.Nanosecond,
+		dial:            ipd.dial,
+	}
+
+	_, err := d.Dial(context.Background(), "example.com:80")
+	ass.NoError(err)
+	ass.Equal([]string{"192.0.2.1:80"}, ipd.getDialed())
+
+	r.mu.Lock()
+	r.addrs = ipAddrs("192.0.2.2")
+	r.mu.Unlock()
+
+	_, err = d.Dial(context.Background(), "example.com:80")
+	ass.NoError(err)
+	ass.Equal([]string{"192.0.2.2:80"}, ipd.getDialed())
+
+	// the last resolved IPs are used
+	r.mu.Lock()
+	r.err = fmt.Errorf("no such host")
+	r.mu.Unlock()
+
+	_, err = d.Dial(context.Background(), "example.com:80")
+	ass.NoError(err)
+	ass.Equal([]string{"192.0.2.2:80"}, ipd.getDialed())
+	ass.Equal(3, r.calls)
+
+	_, err = d.Dial(context.Background(), "unknown.com:80")
+	ass.Error(err)
+}
+
+func TestHappyEyeballsDialerLocalAddr(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	ipd := &testIPDialer{}
+	d := Chain(&HappyEyeballsDialer{
+		Resolver: &testResolver{addrs: ipAddrs("2001:db8::1", "192.0.2.1")},
+		dial:     ipd.dial,
+	}, WithLocalAddr(net.ParseIP("192.0.2.100")))
+
+	// IPv6 addresses can't be dialed from IPv4 local address: they aren't dialed (and aren't marked down)
+	_, err := d.Dial(context.Background(), "example.com:80")
+	ass.NoError(err)
+	ass.Equal([]string{"192.0.2.1:80"}, ipd.getDialed())
+}
+
+func TestHappyEyeballsDialerPrune(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	cl := clock.NewMock()
+	ipd := &testIPDialer{fail: map[string]bool{"192.0.2.1:81": true}}
+
+	d := &HappyEyeballsDialer{
+		Resolver:               &testResolver{addrs: ipAddrs("192.0.2.1")},
+		ResolveInterval:        time.Minute,
+		InitialBackoffInterval: time.Hour,
+		MaxBackoffInterval:     time.Hour,
+		Clock:                  cl,
+		dial:                   ipd.dial,
+	}
+
+	for _, addr := range []string{"a.com:80", "b.com:80", "c.com:81"} {
+		_, _ = d.Dial(context.Background(), addr)
+	}
+
+	ass.Len(d.hosts, 3)
+
+	// "a.com" isn't dialed anymore: it is dropped; IP of "c.com" is in backoff: it is kept
+	cl.Add(2 * time.Minute)
+	_, err := d.Dial(context.Background(), "b.com:80")
+	ass.NoError(err)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ass.Len(d.hosts, 2)
+	ass.NotNil(d.hosts["tcp://b.com:80"])
+	ass.NotNil(d.hosts["tcp://c.com:81"])
+}
@@ -0,0 +1,116 @@
+package goconnpool
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// hedgedAttempt is the result of one DoHedged attempt.
+type hedgedAttempt[T any] struct {
+	r   *resource[T]
+	err error
+}
+
+func (p *pool[T]) DoHedged(ctx context.Context, hedgeDelay time.Duration, maxHedges int,
+	fn func(context.Context, T) error) error {
+
+	return p.doHedged(ctx, hedgeDelay, maxHedges, func(ctx context.Context, r *resource[T]) error {
+		return fn(ctx, r.value)
+	})
+}
+
+func (p *pool[T]) doHedged(ctx context.Context, hedgeDelay time.Duration, maxHedges int,
+	fn func(context.Context, *resource[T]) error) error {
+
+	if maxHedges < 0 {
+		return errors.Errorf("invalid maxHedges %d: should not be negative", maxHedges)
+	}
+
+	if maxHedges > 0 && hedgeDelay <= 0 {
+		return errors.Errorf("invalid hedgeDelay %s: should be positive", hedgeDelay)
+	}
+
+	r, err := p.openConnBlock(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		// buffered for all attempts: goroutines of attempts left after return aren't blocked
+		results = make(chan hedgedAttempt[T], maxHedges+1)
+		pending = map[*resource[T]]bool{}
+		used    = map[connectionProvider[T]]bool{}
+		hedgeC  <-chan time.Time
+		nHedges int
+	)
+
+	start := func(r *resource[T]) {
+		pending[r] = true
+
+		// XXX: serversByAddr is modified only during initialization: p.mu isn't required
+		used[p.serversByAddr[r.ServerAddr()]] = true
+
+		go func() {
+			results <- hedgedAttempt[T]{r: r, err: fn(ctx, r)}
+		}()
+	}
+
+	// losing attempts could be in the middle of the response: their connections can't be reused
+	closePending := func() {
+		for r := range pending {
+			r.Close() // nolint:errcheck
+		}
+	}
+
+	start(r)
+	if maxHedges > 0 {
+		hedgeC = p.cfg.Clock.After(hedgeDelay)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			closePending()
+			return ctx.Err()
+
+		case <-hedgeC:
+			nHedges++
+			if nHedges < maxHedges {
+				hedgeC = p.cfg.Clock.After(hedgeDelay)
+			} else {
+				hedgeC = nil
+			}
+
+			started := p.cfg.Clock.Now()
+
+			// hedged request is sent only to the server which wasn't used yet
+			r, _, err := p.openConn(ctx, used, false)
+			if err != nil {
+				p.log.debug("can't send hedged request", errorField(err))
+				continue
+			}
+
+			p.cfg.Hooks.acquire(r, p.cfg.Clock.Since(started))
+			start(r)
+
+		case res := <-results:
+			delete(pending, res.r)
+
+			if res.err == nil {
+				cancel()
+				closePending()
+				return p.release(res.r, nil)
+			}
+
+			p.release(res.r, res.err) // nolint:errcheck
+			if len(pending) == 0 {
+				return res.err
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package goconnpool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func TestDoHedged(t *testing.T) {
+	t.Parallel()
+
+	const hedgeDelay = 10 * time.Millisecond
+
+	t.Run("invalid_args", func(t *testing.T) {
+		ass := require.New(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		p := newTestRetryPool(t, NewMockDialer(ctrl)) // nothing is dialed
+
+		fn := func(context.Context, Conn) error {
+			ass.Fail("unexpected call")
+			return nil
+		}
+
+		ass.EqualError(p.DoHedged(context.Background(), hedgeDelay, -1, fn),
+			"invalid maxHedges -1: should not be negative")
+		ass.EqualError(p.DoHedged(context.Background(), hedgeDelay, -5, fn),
+			"invalid maxHedges -5: should not be negative")
+		ass.EqualError(p.DoHedged(context.Background(), 0, 1, fn),
+			"invalid hedgeDelay 0s: should be positive")
+		ass.EqualError(p.DoHedged(context.Background(), -time.Second, 2, fn),
+			"invalid hedgeDelay -1s: should be positive")
+	})
+
+	t.Run("no_hedges_allowed", func(t *testing.T) {
+		ass := require.New(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		dialer := NewMockDialer(ctrl)
+		dialer.EXPECT().Dial(gomock.Any(), "a").Return(newTestClosableConn(ctrl, false), nil)
+
+		p := newTestRetryPool(t, dialer)
+
+		// hedgeDelay is ignored when hedged requests aren't allowed
+		ass.NoError(p.DoHedged(context.Background(), 0, 0, func(context.Context, Conn) error { return nil }))
+	})
+
+	t.Run("no_hedge", func(t *testing.T) {
+		ass := require.New(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		dialer := NewMockDialer(ctrl)
+		dialer.EXPECT().Dial(gomock.Any(), "a").Return(newTestClosableConn(ctrl, false), nil)
+
+		p := newTestRetryPool(t, dialer)
+
+		err := p.DoHedged(context.Background(), hedgeDelay, 1, func(_ context.Context, cn Conn) error {
+			ass.Equal("a", cn.ServerAddr())
+			return nil
+		})
+
+		ass.NoError(err)
+		ass.Equal(1, p.Stats().Servers[0].IdleConns)
+	})
+
+	t.Run("hedge_wins", func(t *testing.T) {
+		ass := require.New(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		dialer := NewMockDialer(ctrl)
+		dialer.EXPECT().Dial(gomock.Any(), "a").Return(newTestClosableConn(ctrl, true), nil)
+		dialer.EXPECT().Dial(gomock.Any(), "b").Return(newTestClosableConn(ctrl, false), nil)
+
+		p := newTestRetryPool(t, dialer)
+
+		cancelled := make(chan struct{})
+		err := p.DoHedged(context.Background(), hedgeDelay, 1, func(ctx context.Context, cn Conn) error {
+			if cn.ServerAddr() == "b" {
+				return nil
+			}
+
+			<-ctx.Done()
+			close(cancelled)
+			return ctx.Err()
+		})
+
+		ass.NoError(err)
+		<-cancelled
+
+		st := p.Stats()
+		ass.Equal(0, st.Servers[0].OpenConns)
+		ass.Equal(1, st.Servers[1].IdleConns)
+	})
+
+	t.Run("all_failed", func(t *testing.T) {
+		ass := require.New(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		dialer := NewMockDialer(ctrl)
+		dialer.EXPECT().Dial(gomock.Any(), "a").Return(newTestClosableConn(ctrl, true), nil)
+		dialer.EXPECT().Dial(gomock.Any(), "b").Return(newTestClosableConn(ctrl, false), nil)
+
+		p := newTestRetryPool(t, dialer)
+
+		var (
+			hedged = make(chan struct{})
+			failed = make(chan struct{})
+		)
+
+		err := p.DoHedged(context.Background(), hedgeDelay, 1, func(_ context.Context, cn Conn) error {
+			if cn.ServerAddr() == "b" {
+				close(hedged)
+				<-failed
+				return errTestRequest
+			}
+
+			<-hedged
+			defer close(failed)
+			return errTestBroken
+		})
+
+		ass.Equal(errTestRequest, err)
+
+		st := p.Stats()
+		ass.Equal(0, st.Servers[0].OpenConns)
+		ass.Equal(1, st.Servers[1].IdleConns)
+	})
+
+	t.Run("distinct_servers", func(t *testing.T) {
+		ass := require.New(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		dialer := NewMockDialer(ctrl)
+		dialer.EXPECT().Dial(gomock.Any(), "a").Return(newTestClosableConn(ctrl, false), nil)
+
+		p := newTestRetryPool(t, dialer)
+		ass.NoError(p.SetServerMode("b", ServerModeDown))
+
+		var n int
+		err := p.DoHedged(context.Background(), hedgeDelay, 2, func(_ context.Context, cn Conn) error {
+			n++
+			time.Sleep(5 * hedgeDelay)
+			return nil
+		})
+
+		ass.NoError(err)
+		ass.Equal(1, n)
+	})
+}
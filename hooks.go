@@ -0,0 +1,99 @@
+package goconnpool
+
+import "time"
+
+// Hooks holds callbacks invoked on the pool lifecycle events.
+// Could be used to plug some tracing, metrics or alerting into the pool.
+//
+// Each callback is optional: nil callbacks are skipped.
+//
+// Callbacks are invoked synchronously (most of them under the server lock): they shouldn't block
+// and shouldn't call any pool or connection methods.
+type Hooks struct {
+	// OnDialStart is called before each dial to the server.
+	OnDialStart func(addr string)
+
+	// OnDialDone is called after each dial to the server. err is not nil if the dial was failed.
+	OnDialDone func(addr string, dur time.Duration, err error)
+
+	// OnServerDown is called when the alive server becomes down after failed dial.
+	// retryAfter is the backoff interval: the server won't be dialed during this interval.
+	OnServerDown func(addr string, err error, retryAfter time.Duration)
+
+	// OnServerUp is called when the server marked as down is up again: it was successfully dialed, the callback of
+	// one of Do* calls succeeded using its connection or ServerModeAuto was set with SetServerMode.
+	OnServerUp func(addr string)
+
+	// OnAcquire is called when the connection is returned by one of OpenConn* calls (or borrowed by Do* calls).
+	// wait is the time spent to get the connection.
+	OnAcquire func(addr string, wait time.Duration)
+
+	// OnRelease is called when the connection is returned into pool with ReturnToPool() call.
+	OnRelease func(addr string)
+
+	// OnRatelimited is called when the connection request to the server was rejected because of
+	// MaxRPS, MaxConnsPerServer or backoff limits.
+	OnRatelimited func(addr string, err error)
+
+	// OnConnClosed is called when the connection is closed with Close() call.
+	// err is the error returned by the original connection Close() call.
+	OnConnClosed func(addr string, err error)
+
+	// OnConnLeaked is called once for each connection owned by the user longer than Config.LeakThreshold.
+	// stack is the stack trace of the OpenConn* call returned the connection.
+	OnConnLeaked func(addr string, connID uint64, heldFor time.Duration, stack string)
+}
+
+func (h *Hooks) dialStart(addr string) {
+	if h != nil && h.OnDialStart != nil {
+		h.OnDialStart(addr)
+	}
+}
+
+func (h *Hooks) dialDone(addr string, dur time.Duration, err error) {
+	if h != nil && h.OnDialDone != nil {
+		h.OnDialDone(addr, dur, err)
+	}
+}
+
+func (h *Hooks) serverDown(addr string, err error, retryAfter time.Duration) {
+	if h != nil && h.OnServerDown != nil {
+		h.OnServerDown(addr, err, retryAfter)
+	}
+}
+
+func (h *Hooks) serverUp(addr string) {
+	if h != nil && h.OnServerUp != nil {
+		h.OnServerUp(addr)
+	}
+}
+
+func (h *Hooks) acquire(cn interface{ ServerAddr() string }, wait time.Duration) {
+	if h != nil && h.OnAcquire != nil {
+		h.OnAcquire(cn.ServerAddr(), wait)
+	}
+}
+
+func (h *Hooks) release(addr string) {
+	if h != nil && h.OnRelease != nil {
+		h.OnRelease(addr)
+	}
+}
+
+func (h *Hooks) ratelimited(addr string, err error) {
+	if h != nil && h.OnRatelimited != nil {
+		h.OnRatelimited(addr, err)
+	}
+}
+
+func (h *Hooks) connClosed(addr string, err error) {
+	if h != nil && h.OnConnClosed != nil {
+		h.OnConnClosed(addr, err)
+	}
+}
+
+func (h *Hooks) connLeaked(addr string, connID uint64, heldFor time.Duration, stack string) {
+	if h != nil && h.OnConnLeaked != nil {
+		h.OnConnLeaked(addr, connID, heldFor, stack)
+	}
+}
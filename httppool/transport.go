@@ -0,0 +1,187 @@
+// Package httppool implements http.RoundTripper sending requests using goconnpool.ConnPool connections.
+//
+// Requests are sent into servers registered in the pool (URL host is used only as Host header value) using
+// HTTP/1.1 with keep-alive: MaxRPS limits and backoff of the pool are applied to plain net/http clients.
+//
+//	client := &http.Client{
+//		Transport: httppool.NewTransport(pool),
+//	}
+package httppool
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/derElektrobesen/goconnpool"
+	"github.com/pkg/errors"
+)
+
+// Transport implements http.RoundTripper interface.
+//
+// Connection is returned into pool when the response body is read till EOF (or if the response has no body).
+// Connection is closed if the request or the response has "Connection: close" header, if the body read
+// was failed, if the body was closed before EOF or if the request context was cancelled.
+type Transport struct {
+	pool goconnpool.ConnPool
+}
+
+// NewTransport creates new transport over the pool passed.
+func NewTransport(p goconnpool.ConnPool) *Transport {
+	return &Transport{
+		pool: p,
+	}
+}
+
+// aLongTimeAgo is used to interrupt blocked IO calls.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// RoundTrip implements http.RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "http" {
+		closeBody(req)
+		return nil, errors.Errorf("unsupported protocol scheme %q", req.URL.Scheme)
+	}
+
+	ctx := req.Context()
+
+	cn, err := t.pool.OpenConn(ctx)
+	if err != nil {
+		closeBody(req)
+		return nil, errors.Wrap(err, "can't open connection")
+	}
+
+	stop := watchCancel(ctx, cn)
+
+	if err := req.Write(cn); err != nil {
+		stop()
+		cn.Close() // nolint:errcheck
+		return nil, errors.Wrap(err, "can't write request")
+	}
+
+	br := bufio.NewReader(cn)
+
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		stop()
+		cn.Close() // nolint:errcheck
+		return nil, errors.Wrap(err, "can't read response")
+	}
+
+	b := &body{
+		ReadCloser: resp.Body,
+		cn:         cn,
+		stop:       stop,
+		reusable: func() bool {
+			// connection could be reused only if the response was read completely
+			return !wantsClose(req) && !resp.Close && br.Buffered() == 0 && ctx.Err() == nil
+		},
+	}
+
+	if resp.Body == http.NoBody {
+		b.release(true)
+		return resp, nil
+	}
+
+	resp.Body = b
+	return resp, nil
+}
+
+// wantsClose reports whether the connection should be closed after the request (like net/http does):
+// req.Close is set or "Connection: close" header is passed.
+func wantsClose(req *http.Request) bool {
+	if req.Close {
+		return true
+	}
+
+	for _, v := range req.Header.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "close") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close() // nolint:errcheck
+	}
+}
+
+// watchCancel interrupts IO calls on the connection when the context is cancelled.
+// Returned function stops watching: connection deadline isn't changed after this function returned.
+func watchCancel(ctx context.Context, cn goconnpool.Conn) func() {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+
+	var (
+		done    = make(chan struct{})
+		stopped = make(chan struct{})
+	)
+
+	go func() {
+		defer close(stopped)
+
+		select {
+		case <-ctx.Done():
+			cn.SetDeadline(aLongTimeAgo) // nolint:errcheck
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// body releases the connection when the response body is read or closed.
+type body struct {
+	io.ReadCloser
+
+	cn       goconnpool.Conn
+	stop     func()
+	reusable func() bool
+
+	once sync.Once
+}
+
+func (b *body) release(reuse bool) {
+	b.once.Do(func() {
+		b.stop()
+
+		if reuse && b.reusable() {
+			b.cn.ReturnToPool() // nolint:errcheck
+			return
+		}
+
+		b.cn.Close() // nolint:errcheck
+	})
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.release(true)
+	} else if err != nil {
+		b.release(false)
+	}
+
+	return n, err
+}
+
+func (b *body) Close() error {
+	// connection is already released if the body was read till EOF:
+	// otherwise the rest of the response can't be skipped quickly
+	b.release(false)
+	b.ReadCloser.Close() // nolint:errcheck
+	return nil
+}
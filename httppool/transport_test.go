@@ -0,0 +1,171 @@
+package httppool
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/derElektrobesen/goconnpool"
+	"github.com/derElektrobesen/goconnpool/internal/pooltest"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, h http.HandlerFunc) (*httptest.Server, *int32) {
+	var nConns int32
+
+	srv := httptest.NewUnstartedServer(h)
+	srv.Config.ConnState = func(_ net.Conn, st http.ConnState) {
+		if st == http.StateNew {
+			atomic.AddInt32(&nConns, 1)
+		}
+	}
+
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	return srv, &nConns
+}
+
+func newTestClient(t *testing.T, srv *httptest.Server) (*http.Client, goconnpool.ConnPool) {
+	p := goconnpool.NewConnPool(goconnpool.Config{
+		MaxRPS: math.MaxInt32,
+	})
+
+	pooltest.RegisterServers(t, p, srv.Listener.Addr().String())
+
+	return &http.Client{Transport: NewTransport(p)}, p
+}
+
+func get(t *testing.T, c *http.Client, url string) string {
+	resp, err := c.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return string(b)
+}
+
+func TestTransportKeepAlive(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	srv, nConns := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Host+r.URL.Path) // nolint:errcheck
+	})
+
+	c, p := newTestClient(t, srv)
+
+	ass.Equal("example.com/a", get(t, c, "http://example.com/a"))
+	ass.Equal("example.com/b", get(t, c, "http://example.com/b"))
+
+	resp, err := c.Head("http://example.com/c")
+	ass.NoError(err)
+	ass.Equal(http.StatusOK, resp.StatusCode)
+
+	ass.EqualValues(1, atomic.LoadInt32(nConns))
+
+	st := p.Stats().Servers[0]
+	ass.Equal(1, st.IdleConns)
+	ass.EqualValues(1, st.Dials)
+
+	_, err = c.Get("https://example.com")
+	ass.Error(err)
+}
+
+func TestTransportConnectionClose(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	srv, nConns := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Connection", "close")
+		io.WriteString(w, "ok") // nolint:errcheck
+	})
+
+	c, p := newTestClient(t, srv)
+
+	ass.Equal("ok", get(t, c, "http://example.com"))
+	ass.Equal("ok", get(t, c, "http://example.com"))
+
+	ass.EqualValues(2, atomic.LoadInt32(nConns))
+	ass.Equal(0, p.Stats().Servers[0].OpenConns)
+
+	// "Connection: close" is requested by the header only: response doesn't contain it
+	srv, _ = newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		cn, bw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { cn.Close() }) // nolint:errcheck
+
+		bw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok") // nolint:errcheck
+		bw.Flush()                                                       // nolint:errcheck
+	})
+
+	c, p = newTestClient(t, srv)
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	ass.NoError(err)
+	req.Header.Set("Connection", "close")
+
+	resp, err := c.Do(req)
+	ass.NoError(err)
+	body, err := ioutil.ReadAll(resp.Body)
+	ass.NoError(err)
+	ass.NoError(resp.Body.Close())
+
+	ass.Equal("ok", string(body))
+	ass.Equal(0, p.Stats().Servers[0].OpenConns)
+}
+
+func TestTransportBodyNotRead(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	srv, nConns := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "some long response") // nolint:errcheck
+	})
+
+	c, p := newTestClient(t, srv)
+
+	resp, err := c.Get("http://example.com")
+	ass.NoError(err)
+	ass.NoError(resp.Body.Close())
+
+	ass.Equal(0, p.Stats().Servers[0].OpenConns)
+
+	ass.Equal("some long response", get(t, c, "http://example.com"))
+	ass.EqualValues(2, atomic.LoadInt32(nConns))
+}
+
+func TestTransportCancel(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	block := make(chan struct{})
+	srv, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	})
+	defer close(block)
+
+	c, p := newTestClient(t, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	ass.NoError(err)
+
+	_, err = c.Do(req)
+	ass.Error(err)
+
+	ass.Equal(0, p.Stats().Servers[0].OpenConns)
+}
@@ -0,0 +1,50 @@
+// Package pooltest contains helpers shared by tests of goconnpool subpackages.
+package pooltest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/derElektrobesen/goconnpool"
+)
+
+const (
+	// OK is the address of the server Dialer successfully dials to.
+	OK = "ok"
+
+	// Bad is the address of the server Dialer always fails to dial to.
+	Bad = "bad"
+)
+
+// Dialer returns in-memory connections (see net.Pipe). Dials to Bad address are failed.
+type Dialer struct{}
+
+// Dial implements goconnpool.Dialer interface.
+func (Dialer) Dial(ctx context.Context, address string) (net.Conn, error) {
+	if address == Bad {
+		return nil, fmt.Errorf("connection refused")
+	}
+
+	cn, _ := net.Pipe()
+	return cn, nil
+}
+
+// RegisterServers registers servers passed in the pool (OK and Bad if no addresses are passed).
+// Test is failed if any server can't be registered.
+func RegisterServers(t testing.TB, p interface {
+	RegisterServer(addr string, opts ...goconnpool.ServerOption) error
+}, addrs ...string) {
+	t.Helper()
+
+	if len(addrs) == 0 {
+		addrs = []string{OK, Bad}
+	}
+
+	for _, addr := range addrs {
+		if err := p.RegisterServer(addr); err != nil {
+			t.Fatalf("can't register server %s: %s", addr, err)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package goconnpool
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ServerSpec holds the server loaded with one of Load* functions.
+type ServerSpec struct {
+	Addr    string
+	Options []ServerOption
+}
+
+// LoadedConfig holds the pool configuration and the servers list loaded with one of Load* functions.
+type LoadedConfig struct {
+	Config  Config
+	Servers []ServerSpec
+}
+
+// RegisterServers registers loaded servers in the pool passed (ConnPool or Pool[T]).
+func (c *LoadedConfig) RegisterServers(p interface {
+	RegisterServer(addr string, opts ...ServerOption) error
+}) error {
+	for _, s := range c.Servers {
+		if err := p.RegisterServer(s.Addr, s.Options...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fileConfig is the format of YAML and JSON documents. Names of the pool fields are the same NewConfig uses.
+type fileConfig struct {
+	MaxConnsPerServer      int          `json:"max_conns_per_server" yaml:"max_conns_per_server"`
+	MaxStreamsPerConn      int          `json:"max_streams_per_conn" yaml:"max_streams_per_conn"`
+	MaxRPS                 int          `json:"max_rps" yaml:"max_rps"`
+	ConnectTimeout         fileDuration `json:"connect_timeout" yaml:"connect_timeout"`
+	InitialBackoffInterval fileDuration `json:"init_backoff_interval" yaml:"init_backoff_interval"`
+	MaxBackoffInterval     fileDuration `json:"max_backoff_interval" yaml:"max_backoff_interval"`
+	LeakThreshold          fileDuration `json:"leak_threshold" yaml:"leak_threshold"`
+
+	Servers []fileServer `json:"servers" yaml:"servers"`
+}
+
+// fileServer holds the server address and its overrides (see ServerOption).
+type fileServer struct {
+	Addr                   string       `json:"addr" yaml:"addr"`
+	MaxConns               int          `json:"max_conns" yaml:"max_conns"`
+	MaxStreams             int          `json:"max_streams" yaml:"max_streams"`
+	MaxRPS                 int          `json:"max_rps" yaml:"max_rps"`
+	ConnectTimeout         fileDuration `json:"connect_timeout" yaml:"connect_timeout"`
+	InitialBackoffInterval fileDuration `json:"init_backoff_interval" yaml:"init_backoff_interval"`
+	MaxBackoffInterval     fileDuration `json:"max_backoff_interval" yaml:"max_backoff_interval"`
+}
+
+// fileDuration is the duration written in time.ParseDuration format ("1s", "250ms").
+type fileDuration time.Duration
+
+func (d *fileDuration) set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.Errorf("invalid duration %q", s)
+	}
+
+	*d = fileDuration(v)
+	return nil
+}
+
+func (d *fileDuration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.Errorf("invalid duration %s: string like \"1s\" is expected", b)
+	}
+
+	return d.set(s)
+}
+
+func (d *fileDuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	return d.set(s)
+}
+
+// LoadYAML loads the pool configuration and the servers list from YAML document:
+//
+//	max_conns_per_server: 4
+//	max_rps: 100
+//	connect_timeout: 1s
+//	servers:
+//	  - addr: primary:5432
+//	    max_rps: 1000
+//	  - addr: replica:5432
+//	    connect_timeout: 3s
+//
+// Pool fields have the same names NewConfig flags have (plus leak_threshold). Servers fields are addr and
+// overrides: max_conns, max_streams, max_rps, connect_timeout, init_backoff_interval and max_backoff_interval.
+// Unknown fields are rejected. The configuration loaded is checked with Config.Validate.
+func LoadYAML(r io.Reader) (*LoadedConfig, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't read YAML config")
+	}
+
+	var fc fileConfig
+	if err := yaml.UnmarshalStrict(data, &fc); err != nil {
+		return nil, errors.Wrap(err, "invalid YAML config")
+	}
+
+	return fc.load()
+}
+
+// LoadJSON loads the pool configuration and the servers list from JSON document.
+// The format is the same LoadYAML uses. Unknown fields are rejected.
+func LoadJSON(r io.Reader) (*LoadedConfig, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var fc fileConfig
+	if err := dec.Decode(&fc); err != nil {
+		return nil, errors.Wrap(err, "invalid JSON config")
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid JSON config: unexpected data after the document")
+	}
+
+	return fc.load()
+}
+
+func (fc fileConfig) load() (*LoadedConfig, error) {
+	err := firstError(
+		checkNonNegative("max_conns_per_server", fc.MaxConnsPerServer),
+		checkNonNegative("max_streams_per_conn", fc.MaxStreamsPerConn),
+		checkNonNegative("max_rps", fc.MaxRPS),
+		checkNonNegative("connect_timeout", fc.ConnectTimeout),
+		checkNonNegative("init_backoff_interval", fc.InitialBackoffInterval),
+		checkNonNegative("max_backoff_interval", fc.MaxBackoffInterval),
+		checkNonNegative("leak_threshold", fc.LeakThreshold),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	lc := &LoadedConfig{
+		Config: Config{
+			MaxConnsPerServer:      fc.MaxConnsPerServer,
+			MaxStreamsPerConn:      fc.MaxStreamsPerConn,
+			MaxRPS:                 fc.MaxRPS,
+			ConnectTimeout:         time.Duration(fc.ConnectTimeout),
+			InitialBackoffInterval: time.Duration(fc.InitialBackoffInterval),
+			MaxBackoffInterval:     time.Duration(fc.MaxBackoffInterval),
+			LeakThreshold:          time.Duration(fc.LeakThreshold),
+		},
+	}
+
+	if err := lc.Config.Validate(); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for i, s := range fc.Servers {
+		spec, err := s.load(lc.Config)
+		if err != nil {
+			return nil, errors.Wrapf(err, "servers[%d]", i)
+		}
+
+		if seen[spec.Addr] {
+			return nil, errors.Errorf("servers[%d]: duplicate addr %q", i, spec.Addr)
+		}
+
+		seen[spec.Addr] = true
+		lc.Servers = append(lc.Servers, spec)
+	}
+
+	return lc, nil
+}
+
+// load returns the server spec. Effective config of the server (cfg with overrides applied) is validated.
+func (s fileServer) load(cfg Config) (ServerSpec, error) {
+	if s.Addr == "" {
+		return ServerSpec{}, errors.New("addr is required")
+	}
+
+	err := firstError(
+		checkNonNegative("max_conns", s.MaxConns),
+		checkNonNegative("max_streams", s.MaxStreams),
+		checkNonNegative("max_rps", s.MaxRPS),
+		checkNonNegative("connect_timeout", s.ConnectTimeout),
+		checkNonNegative("init_backoff_interval", s.InitialBackoffInterval),
+		checkNonNegative("max_backoff_interval", s.MaxBackoffInterval),
+	)
+	if err != nil {
+		return ServerSpec{}, err
+	}
+
+	spec := ServerSpec{Addr: s.Addr}
+	add := func(set bool, o ServerOption) {
+		if set {
+			spec.Options = append(spec.Options, o)
+		}
+	}
+
+	add(s.MaxConns > 0, ServerMaxConns(s.MaxConns))
+	add(s.MaxStreams > 0, ServerMaxStreams(s.MaxStreams))
+	add(s.MaxRPS > 0, ServerMaxRPS(s.MaxRPS))
+	add(s.ConnectTimeout > 0, ServerConnectTimeout(time.Duration(s.ConnectTimeout)))
+	add(s.InitialBackoffInterval > 0 || s.MaxBackoffInterval > 0,
+		ServerBackoff(time.Duration(s.InitialBackoffInterval), time.Duration(s.MaxBackoffInterval)))
+
+	if _, err := serverConfig(cfg, spec.Options); err != nil {
+		return ServerSpec{}, err
+	}
+
+	return spec, nil
+}
+
+func checkNonNegative[V int | fileDuration](name string, v V) error {
+	if v < 0 {
+		return errors.Errorf("%s: negative value isn't allowed", name)
+	}
+
+	return nil
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadEnv loads the pool configuration and the servers list from environment variables with prefix passed.
+//
+// Variables have the same names NewConfig flags have (plus leak_threshold) in upper case:
+//
+//	APP_POOL_MAX_RPS=100
+//	APP_POOL_CONNECT_TIMEOUT=1s
+//	APP_POOL_SERVERS=primary:5432?max_rps=1000,replica:5432?connect_timeout=3s
+//
+// SERVERS is the comma-separated list of addresses. Server overrides (see LoadYAML) could be passed in the
+// query string format. Unknown variables with prefix passed are rejected.
+func LoadEnv(prefix string) (*LoadedConfig, error) {
+	return loadEnv(prefix, os.Environ())
+}
+
+func loadEnv(prefix string, environ []string) (*LoadedConfig, error) {
+	vars := map[string]string{}
+	for _, kv := range environ {
+		if !strings.HasPrefix(kv, prefix) {
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(kv, prefix), "=", 2)
+		if len(parts) == 2 {
+			vars[strings.ToLower(parts[0])] = parts[1]
+		}
+	}
+
+	var fc fileConfig
+	fields := map[string]func(string) error{
+		"max_conns_per_server":  intSetter(&fc.MaxConnsPerServer),
+		"max_streams_per_conn":  intSetter(&fc.MaxStreamsPerConn),
+		"max_rps":               intSetter(&fc.MaxRPS),
+		"connect_timeout":       fc.ConnectTimeout.set,
+		"init_backoff_interval": fc.InitialBackoffInterval.set,
+		"max_backoff_interval":  fc.MaxBackoffInterval.set,
+		"leak_threshold":        fc.LeakThreshold.set,
+		"servers": func(v string) (err error) {
+			fc.Servers, err = parseEnvServers(v)
+			return err
+		},
+	}
+
+	// variables are processed in the sorted order: the first error is always the same
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		set, ok := fields[name]
+		if !ok {
+			return nil, errors.Errorf("unknown variable %s%s", prefix, strings.ToUpper(name))
+		}
+
+		if err := set(vars[name]); err != nil {
+			return nil, errors.Wrapf(err, "invalid variable %s%s", prefix, strings.ToUpper(name))
+		}
+	}
+
+	return fc.load()
+}
+
+func intSetter(dst *int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.Errorf("invalid integer %q", v)
+		}
+
+		*dst = n
+		return nil
+	}
+}
+
+func parseEnvServers(v string) ([]fileServer, error) {
+	var res []fileServer
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		s := fileServer{Addr: entry}
+		if i := strings.LastIndex(entry, "?"); i >= 0 {
+			s.Addr = entry[:i]
+
+			query, err := url.ParseQuery(entry[i+1:])
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid server %q", entry)
+			}
+
+			if err := s.setOverrides(query); err != nil {
+				return nil, errors.Wrapf(err, "invalid server %q", entry)
+			}
+		}
+
+		res = append(res, s)
+	}
+
+	return res, nil
+}
+
+func (s *fileServer) setOverrides(query url.Values) error {
+	fields := map[string]func(string) error{
+		"max_conns":             intSetter(&s.MaxConns),
+		"max_streams":           intSetter(&s.MaxStreams),
+		"max_rps":               intSetter(&s.MaxRPS),
+		"connect_timeout":       s.ConnectTimeout.set,
+		"init_backoff_interval": s.InitialBackoffInterval.set,
+		"max_backoff_interval":  s.MaxBackoffInterval.set,
+	}
+
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		set, ok := fields[name]
+		if !ok {
+			return errors.Errorf("unknown field %q", name)
+		}
+
+		if err := set(query.Get(name)); err != nil {
+			return errors.Wrap(err, name)
+		}
+	}
+
+	return nil
+}
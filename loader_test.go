@@ -0,0 +1,148 @@
+package goconnpool
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func applyServerOptions(opts []ServerOption) Config {
+	var cfg Config
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	return cfg
+}
+
+func TestLoadYAML(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	lc, err := LoadYAML(strings.NewReader(`
+max_conns_per_server: 4
+max_rps: 100
+connect_timeout: 1s
+leak_threshold: 1m
+servers:
+  - addr: primary:5432
+    max_rps: 1000
+    max_conns: 8
+  - addr: replica:5432
+    connect_timeout: 3s
+    init_backoff_interval: 10ms
+`))
+	ass.NoError(err)
+	ass.Equal(Config{
+		MaxConnsPerServer: 4,
+		MaxRPS:            100,
+		ConnectTimeout:    time.Second,
+		LeakThreshold:     time.Minute,
+	}, lc.Config)
+
+	ass.Len(lc.Servers, 2)
+	ass.Equal("primary:5432", lc.Servers[0].Addr)
+	ass.Equal(Config{MaxConnsPerServer: 8, MaxRPS: 1000}, applyServerOptions(lc.Servers[0].Options))
+	ass.Equal("replica:5432", lc.Servers[1].Addr)
+	ass.Equal(Config{ConnectTimeout: 3 * time.Second, InitialBackoffInterval: 10 * time.Millisecond},
+		applyServerOptions(lc.Servers[1].Options))
+
+	for doc, msg := range map[string]string{
+		"max_rpss: 1":                              "field max_rpss not found",
+		"max_rps: -1":                              "max_rps: negative value isn't allowed",
+		"init_backoff_interval: 1m":                "MaxBackoffInterval (30s) should not be less",
+		"connect_timeout: soon":                    `invalid duration "soon"`,
+		"servers: [{max_rps: 1}]":                  "servers[0]: addr is required",
+		"servers: [{addr: a}, {addr: a}]":          `servers[1]: duplicate addr "a"`,
+		"servers: [{addr: a, max_conns: -2}]":      "servers[0]: max_conns: negative value isn't allowed",
+		"servers: [{addr: a, connect_timeout: 1}]": `invalid duration "1"`,
+		"servers: [{addr: a, max_backoff_interval: 50ms}]": "servers[0]: invalid config: MaxBackoffInterval (50ms) " +
+			"should not be less than InitialBackoffInterval (100ms)",
+		"{max_backoff_interval: 1m, servers: [{addr: a, init_backoff_interval: 2m}]}": "servers[0]: invalid config",
+	} {
+		_, err := LoadYAML(strings.NewReader(doc))
+		ass.Error(err, doc)
+		ass.Contains(err.Error(), msg, doc)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	lc, err := LoadJSON(strings.NewReader(`{
+		"max_streams_per_conn": 16,
+		"max_backoff_interval": "5s",
+		"servers": [{"addr": "tcp://primary:5432", "max_backoff_interval": "1m"}]
+	}`))
+	ass.NoError(err)
+	ass.Equal(Config{MaxStreamsPerConn: 16, MaxBackoffInterval: 5 * time.Second}, lc.Config)
+	ass.Len(lc.Servers, 1)
+	ass.Equal("tcp://primary:5432", lc.Servers[0].Addr)
+	ass.Equal(Config{MaxBackoffInterval: time.Minute}, applyServerOptions(lc.Servers[0].Options))
+
+	for doc, msg := range map[string]string{
+		`{"unknown": 1}`:              `unknown field "unknown"`,
+		`{"max_rps": "1"}`:            "invalid JSON config",
+		`{"connect_timeout": 1}`:      "invalid duration 1",
+		`{"servers": [{"addr": ""}]}`: "servers[0]: addr is required",
+		`{"leak_threshold": "-1s"}`:   "leak_threshold: negative value isn't allowed",
+		`{} {}`:                       "unexpected data after the document",
+	} {
+		_, err := LoadJSON(strings.NewReader(doc))
+		ass.Error(err, doc)
+		ass.Contains(err.Error(), msg, doc)
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	lc, err := loadEnv("APP_POOL_", []string{
+		"PATH=/bin",
+		"APP_POOL_MAX_RPS=100",
+		"APP_POOL_CONNECT_TIMEOUT=1s",
+		"APP_POOL_SERVERS=primary:5432?max_rps=1000&max_streams=4, replica:5432",
+	})
+	ass.NoError(err)
+	ass.Equal(Config{MaxRPS: 100, ConnectTimeout: time.Second}, lc.Config)
+	ass.Len(lc.Servers, 2)
+	ass.Equal("primary:5432", lc.Servers[0].Addr)
+	ass.Equal(Config{MaxRPS: 1000, MaxStreamsPerConn: 4}, applyServerOptions(lc.Servers[0].Options))
+	ass.Equal("replica:5432", lc.Servers[1].Addr)
+	ass.Empty(lc.Servers[1].Options)
+
+	for env, msg := range map[string]string{
+		"APP_POOL_MAX_RSP=1":                   "unknown variable APP_POOL_MAX_RSP",
+		"APP_POOL_MAX_RPS=many":                `invalid variable APP_POOL_MAX_RPS: invalid integer "many"`,
+		"APP_POOL_MAX_RPS=-1":                  "max_rps: negative value isn't allowed",
+		"APP_POOL_SERVERS=a?max_rsp=1":         `invalid variable APP_POOL_SERVERS: invalid server "a?max_rsp=1": unknown field "max_rsp"`,
+		"APP_POOL_SERVERS=a?connect_timeout=x": `connect_timeout: invalid duration "x"`,
+		"APP_POOL_SERVERS=a,a":                 `servers[1]: duplicate addr "a"`,
+	} {
+		_, err := loadEnv("APP_POOL_", []string{env})
+		ass.Error(err, env)
+		ass.Contains(err.Error(), msg, env)
+	}
+}
+
+func TestLoadedConfigRegisterServers(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	lc, err := loadEnv("APP_", []string{"APP_SERVERS=127.0.0.1:1?max_conns=3,unix:///tmp/sock"})
+	ass.NoError(err)
+
+	p := NewConnPool(lc.Config)
+	ass.NoError(lc.RegisterServers(p))
+
+	st := p.Stats().Servers
+	ass.Len(st, 2)
+	ass.Equal(3, st[0].Limits.MaxConns)
+
+	lc.Servers = append(lc.Servers, ServerSpec{Addr: "bad://addr"})
+	ass.Error(lc.RegisterServers(NewConnPool(lc.Config)))
+}
@@ -1,13 +1,158 @@
 package goconnpool
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // Logger interface is used to log some messages into user log.
+//
+// Logger could also implement StructuredLogger interface to receive messages with structured fields.
 type Logger interface {
 	Errorf(format string, args ...interface{})
 	Infof(format string, args ...interface{})
 }
 
+// Level is the importance of the message passed into StructuredLogger.
+type Level int
+
+const (
+	// LevelDebug is used for noisy events like ratelimited requests or connections reuse.
+	LevelDebug Level = iota
+
+	// LevelInfo is used for events useful to understand the pool state.
+	LevelInfo
+
+	// LevelError is used for errors like failed dials.
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+}
+
+// Keys of the fields passed into StructuredLogger.
+const (
+	// FieldServer is the address of the registered server.
+	FieldServer = "server"
+
+	// FieldError is the error occurred.
+	FieldError = "error"
+
+	// FieldRetryAfter is the time.Duration to wait before the next attempt.
+	FieldRetryAfter = "retry_after"
+
+	// FieldConnID is the unique identifier of the connection dialed by the pool.
+	// Identifier is the same while the connection is reused.
+	FieldConnID = "conn_id"
+
+	// FieldMode is the ServerMode of the registered server.
+	FieldMode = "mode"
+
+	// FieldHeldFor is the time.Duration the connection is owned by the user.
+	FieldHeldFor = "held_for"
+
+	// FieldStack is the stack trace of the OpenConn* call returned the connection.
+	FieldStack = "stack"
+)
+
+// Field is the key-value pair attached to the log message.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// StructuredLogger could be implemented by the Logger to receive messages with structured fields.
+//
+// Messages with LevelDebug are passed only into StructuredLogger: they are skipped for the plain Logger.
+type StructuredLogger interface {
+	Log(level Level, msg string, fields ...Field)
+}
+
 // DummyLogger is used to skip any message printed by the package.
 type DummyLogger struct{}
 
 func (DummyLogger) Errorf(format string, args ...interface{}) {}
 func (DummyLogger) Infof(format string, args ...interface{})  {}
+
+func serverField(addr string) Field {
+	return Field{Key: FieldServer, Value: addr}
+}
+
+func errorField(err error) Field {
+	return Field{Key: FieldError, Value: err}
+}
+
+func retryAfterField(d time.Duration) Field {
+	return Field{Key: FieldRetryAfter, Value: d}
+}
+
+func connIDField(id uint64) Field {
+	return Field{Key: FieldConnID, Value: id}
+}
+
+func modeField(mode ServerMode) Field {
+	return Field{Key: FieldMode, Value: mode}
+}
+
+func heldForField(d time.Duration) Field {
+	return Field{Key: FieldHeldFor, Value: d}
+}
+
+func stackField(stack string) Field {
+	return Field{Key: FieldStack, Value: stack}
+}
+
+// logger passes messages into StructuredLogger or formats them for the plain Logger.
+type logger struct {
+	l Logger
+}
+
+func (l logger) log(level Level, msg string, fields ...Field) {
+	if sl, ok := l.l.(StructuredLogger); ok {
+		sl.Log(level, msg, fields...)
+		return
+	}
+
+	switch level {
+	case LevelError:
+		l.l.Errorf("%s", formatMessage(msg, fields))
+	case LevelInfo:
+		l.l.Infof("%s", formatMessage(msg, fields))
+	}
+}
+
+func (l logger) debug(msg string, fields ...Field) {
+	l.log(LevelDebug, msg, fields...)
+}
+
+func (l logger) info(msg string, fields ...Field) {
+	l.log(LevelInfo, msg, fields...)
+}
+
+func (l logger) error(msg string, fields ...Field) {
+	l.log(LevelError, msg, fields...)
+}
+
+func formatMessage(msg string, fields []Field) string {
+	if len(fields) == 0 {
+		return msg
+	}
+
+	parts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		parts = append(parts, fmt.Sprintf("%s=%v", f.Key, f.Value))
+	}
+
+	return msg + ": " + strings.Join(parts, " ")
+}
@@ -0,0 +1,39 @@
+package goconnpool
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogLogger(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	var buf bytes.Buffer
+	l := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+
+	l.Infof("hello %d", 1)
+	l.Errorf("bye")
+	l.Log(LevelDebug, "ratelimited", serverField("y"), errorField(fmt.Errorf("xxx")))
+	l.Log(LevelError, "dial failed", serverField("y"), retryAfterField(time.Second))
+
+	ass.Equal(
+		"level=INFO msg=\"hello 1\"\n"+
+			"level=ERROR msg=bye\n"+
+			"level=DEBUG msg=ratelimited server=y error=xxx\n"+
+			"level=ERROR msg=\"dial failed\" server=y retry_after=1s\n",
+		buf.String())
+}
@@ -0,0 +1,68 @@
+package goconnpool
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingLogger struct {
+	msgs []string
+}
+
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.msgs = append(l.msgs, "E "+fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Infof(format string, args ...interface{}) {
+	l.msgs = append(l.msgs, "I "+fmt.Sprintf(format, args...))
+}
+
+type recordingStructuredLogger struct {
+	recordingLogger
+	levels []Level
+	fields [][]Field
+}
+
+func (l *recordingStructuredLogger) Log(level Level, msg string, fields ...Field) {
+	l.levels = append(l.levels, level)
+	l.msgs = append(l.msgs, msg)
+	l.fields = append(l.fields, fields)
+}
+
+func TestPlainLogger(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	rec := &recordingLogger{}
+	l := logger{l: rec}
+
+	l.debug("skipped", serverField("y"))
+	l.info("info")
+	l.error("error", serverField("y"), errorField(fmt.Errorf("xxx")), retryAfterField(time.Second), connIDField(5))
+
+	ass.Equal([]string{
+		"I info",
+		"E error: server=y error=xxx retry_after=1s conn_id=5",
+	}, rec.msgs)
+}
+
+func TestStructuredLogger(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	rec := &recordingStructuredLogger{}
+	l := logger{l: rec}
+
+	l.debug("debug", serverField("y"))
+	l.error("error", connIDField(5))
+
+	ass.Equal([]string{"debug", "error"}, rec.msgs)
+	ass.Equal([]Level{LevelDebug, LevelError}, rec.levels)
+	ass.Equal([][]Field{
+		{{Key: FieldServer, Value: "y"}},
+		{{Key: FieldConnID, Value: uint64(5)}},
+	}, rec.fields)
+}
@@ -0,0 +1,205 @@
+package goconnpool
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DialerFunc type is an adapter to allow the use of ordinary functions as Dialer.
+type DialerFunc func(ctx context.Context, address string) (net.Conn, error)
+
+// Dial calls f(ctx, address).
+func (f DialerFunc) Dial(ctx context.Context, address string) (net.Conn, error) {
+	return f(ctx, address)
+}
+
+// DialerMiddleware adds some behaviour (logging, metrics, fault injection, ...) around the Dialer passed.
+type DialerMiddleware func(Dialer) Dialer
+
+// Chain wraps the dialer with middlewares passed.
+// The first middleware is the outermost one: it is invoked first on each Dial call.
+//
+//	cfg.Dialer = goconnpool.Chain(&goconnpool.TCPDialer{},
+//		goconnpool.WithDialTiming(observe),
+//		goconnpool.WithDialRetries(3, 10*time.Millisecond),
+//		goconnpool.WithDialTimeout(time.Second))
+//
+// Address validation (see AddressValidator) is delegated to the dialer passed.
+func Chain(d Dialer, mws ...DialerMiddleware) Dialer {
+	wrapped := d
+	for i := len(mws) - 1; i >= 0; i-- {
+		wrapped = mws[i](wrapped)
+	}
+
+	if v, ok := d.(AddressValidator); ok {
+		return chainedDialer{Dialer: wrapped, v: v}
+	}
+
+	return wrapped
+}
+
+type chainedDialer struct {
+	Dialer
+	v AddressValidator
+}
+
+func (d chainedDialer) ValidateAddress(address string) error {
+	return d.v.ValidateAddress(address)
+}
+
+// WithDialTiming calls the callback passed after each dial with its duration and result.
+// Duration is measured using Config.Clock of the pool (SystemClock if the dialer is used outside of the pool).
+func WithDialTiming(cb func(address string, took time.Duration, err error)) DialerMiddleware {
+	return func(next Dialer) Dialer {
+		return DialerFunc(func(ctx context.Context, address string) (net.Conn, error) {
+			clock := contextClock(ctx)
+			start := clock.Now()
+			cn, err := next.Dial(ctx, address)
+			cb(address, clock.Since(start), err)
+			return cn, err
+		})
+	}
+}
+
+// WithDialTimeout limits the duration of each dial made by the next dialer.
+// Could be used with WithDialRetries to limit each attempt: Config.ConnectTimeout limits the whole Dial call.
+func WithDialTimeout(timeout time.Duration) DialerMiddleware {
+	return func(next Dialer) Dialer {
+		return DialerFunc(func(ctx context.Context, address string) (net.Conn, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next.Dial(ctx, address)
+		})
+	}
+}
+
+// WithDialRetries retries failed dials up to attempts times (including the first one) waiting delay between
+// attempts. Retries are made within one Dial call: the server isn't marked down until all attempts are failed.
+// Context passed into Dial (limited by Config.ConnectTimeout) is respected.
+//
+// Attempts less than 1 are treated as 1. Delay is measured using Config.Clock of the pool
+// (SystemClock if the dialer is used outside of the pool).
+func WithDialRetries(attempts int, delay time.Duration) DialerMiddleware {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	return func(next Dialer) Dialer {
+		return DialerFunc(func(ctx context.Context, address string) (net.Conn, error) {
+			clock := contextClock(ctx)
+
+			var lastErr error
+			for i := 0; i < attempts; i++ {
+				if i > 0 {
+					select {
+					case <-ctx.Done():
+						return nil, errors.Wrapf(lastErr, "dial retries interrupted (%s)", ctx.Err())
+					case <-clock.After(delay):
+					}
+				}
+
+				cn, err := next.Dial(ctx, address)
+				if err == nil {
+					return cn, nil
+				}
+
+				lastErr = err
+			}
+
+			return nil, lastErr
+		})
+	}
+}
+
+type localAddrKey struct{}
+
+// WithLocalAddr binds connections dialed by the next dialer to the local IP passed.
+// Binding is supported by TCPDialer and dialers using it (TLSDialer, ProxyDialer, HappyEyeballsDialer):
+// hostnames of "tcp" addresses are resolved to IPs of the local IP family only (as "tcp4" or "tcp6" ones).
+func WithLocalAddr(ip net.IP) DialerMiddleware {
+	return func(next Dialer) Dialer {
+		return DialerFunc(func(ctx context.Context, address string) (net.Conn, error) {
+			return next.Dial(context.WithValue(ctx, localAddrKey{}, ip), address)
+		})
+	}
+}
+
+// WithInterface binds connections dialed by the next dialer to the network interface with name passed
+// (see WithLocalAddr). The first IPv4 address of the interface is used (or the first IPv6 one if there is no IPv4
+// addresses).
+func WithInterface(name string) DialerMiddleware {
+	return func(next Dialer) Dialer {
+		return DialerFunc(func(ctx context.Context, address string) (net.Conn, error) {
+			ip, err := interfaceIP(name)
+			if err != nil {
+				return nil, err
+			}
+
+			return next.Dial(context.WithValue(ctx, localAddrKey{}, ip), address)
+		})
+	}
+}
+
+func interfaceIP(name string) (net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't find interface %s", name)
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't get addresses of interface %s", name)
+	}
+
+	var v6 net.IP
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP, nil
+		}
+
+		if v6 == nil {
+			v6 = ipNet.IP
+		}
+	}
+
+	if v6 == nil {
+		return nil, errors.Errorf("interface %s has no IP addresses", name)
+	}
+
+	return v6, nil
+}
+
+// boundNetwork narrows "tcp" network to the family of the local address set by WithLocalAddr (if any).
+func boundNetwork(ctx context.Context, network string) string {
+	ip, ok := ctx.Value(localAddrKey{}).(net.IP)
+	if !ok || ip == nil || network != "tcp" {
+		return network
+	}
+
+	if ip.To4() != nil {
+		return "tcp4"
+	}
+
+	return "tcp6"
+}
+
+// netDialer returns net.Dialer bound to the local address set by WithLocalAddr (if any).
+func netDialer(ctx context.Context, network string, d *net.Dialer) *net.Dialer {
+	ip, ok := ctx.Value(localAddrKey{}).(net.IP)
+	if !ok || ip == nil || !strings.HasPrefix(network, "tcp") {
+		return d
+	}
+
+	bound := *d
+	bound.LocalAddr = &net.TCPAddr{IP: ip}
+	return &bound
+}
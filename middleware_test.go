@@ -0,0 +1,187 @@
+package goconnpool
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	var calls []string
+	mw := func(name string) DialerMiddleware {
+		return func(next Dialer) Dialer {
+			return DialerFunc(func(ctx context.Context, address string) (net.Conn, error) {
+				calls = append(calls, name)
+				return next.Dial(ctx, address)
+			})
+		}
+	}
+
+	d := Chain(DialerFunc(func(ctx context.Context, address string) (net.Conn, error) {
+		calls = append(calls, "dialer")
+		return nil, nil
+	}), mw("a"), mw("b"))
+
+	_, err := d.Dial(context.Background(), "addr")
+	ass.NoError(err)
+	ass.Equal([]string{"a", "b", "dialer"}, calls)
+
+	// addresses are validated by the dialer
+	d = Chain(&TCPDialer{}, mw("a"))
+	ass.Error(d.(AddressValidator).ValidateAddress("udp://127.0.0.1:80"))
+}
+
+func TestDialRetries(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	var (
+		attempts int
+		timings  []error
+	)
+
+	d := Chain(DialerFunc(func(ctx context.Context, address string) (net.Conn, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, fmt.Errorf("connection refused")
+		}
+
+		_, deadlineSet := ctx.Deadline()
+		ass.True(deadlineSet)
+
+		return &net.IPConn{}, nil
+	}),
+		WithDialTiming(func(address string, took time.Duration, err error) {
+			ass.Equal("addr", address)
+			timings = append(timings, err)
+		}),
+		WithDialRetries(3, time.Millisecond),
+		WithDialTimeout(time.Second),
+	)
+
+	_, err := d.Dial(context.Background(), "addr")
+	ass.NoError(err)
+	ass.Equal(3, attempts)
+	ass.Equal([]error{nil}, timings)
+
+	attempts = -10
+	_, err = d.Dial(context.Background(), "addr")
+	ass.Error(err)
+	ass.Equal(-7, attempts)
+	ass.Len(timings, 2)
+
+	// retries are interrupted by the context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts = -10
+	_, err = Chain(d, WithDialRetries(100, time.Hour)).Dial(ctx, "addr")
+	ass.Error(err)
+	ass.Equal(-9, attempts)
+
+	var refused int
+	refusedDialer := DialerFunc(func(ctx context.Context, address string) (net.Conn, error) {
+		refused++
+		return nil, fmt.Errorf("connection refused")
+	})
+
+	// dial is made at least once
+	_, err = Chain(refusedDialer, WithDialRetries(0, time.Hour)).Dial(context.Background(), "addr")
+	ass.Error(err)
+	ass.Equal(1, refused)
+
+	// delay is measured by the clock of the pool
+	cl := clock.NewMock()
+	done := make(chan error, 1)
+
+	go func() {
+		_, err := Chain(refusedDialer, WithDialRetries(3, time.Hour)).Dial(withClock(context.Background(), cl), "addr")
+		done <- err
+	}()
+
+	for {
+		select {
+		case err := <-done:
+			ass.Error(err)
+			ass.Equal(4, refused)
+			return
+		case <-time.After(time.Millisecond):
+			cl.Add(time.Hour)
+		}
+	}
+}
+
+func TestDialTiming(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	// duration is measured by the clock of the pool
+	cl := clock.NewMock()
+	d := Chain(DialerFunc(func(ctx context.Context, address string) (net.Conn, error) {
+		cl.Add(time.Minute)
+		return nil, fmt.Errorf("refused")
+	}), WithDialTiming(func(address string, took time.Duration, err error) {
+		ass.Equal("addr", address)
+		ass.Equal(time.Minute, took)
+		ass.EqualError(err, "refused")
+	}))
+
+	_, err := d.Dial(withClock(context.Background(), cl), "addr")
+	ass.Error(err)
+}
+
+func TestDialLocalAddr(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	ass.NoError(err)
+	defer l.Close()
+
+	remotes := make(chan string, 2)
+	go func() {
+		for {
+			cn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			host, _, _ := net.SplitHostPort(cn.RemoteAddr().String())
+			remotes <- host
+			cn.Close()
+		}
+	}()
+
+	cn, err := Chain(&TCPDialer{}, WithLocalAddr(net.ParseIP("127.0.0.2"))).
+		Dial(context.Background(), l.Addr().String())
+	ass.NoError(err)
+	ass.Equal("127.0.0.2", <-remotes)
+	ass.NoError(cn.Close())
+
+	// hostnames are resolved to the local address family only
+	v4 := context.WithValue(context.Background(), localAddrKey{}, net.ParseIP("127.0.0.2"))
+	v6 := context.WithValue(context.Background(), localAddrKey{}, net.ParseIP("::1"))
+	ass.Equal("tcp4", boundNetwork(v4, "tcp"))
+	ass.Equal("tcp6", boundNetwork(v6, "tcp"))
+	ass.Equal("tcp4", boundNetwork(v6, "tcp4"))
+	ass.Equal("unix", boundNetwork(v6, "unix"))
+	ass.Equal("tcp", boundNetwork(context.Background(), "tcp"))
+
+	_, err = Chain(&TCPDialer{}, WithInterface("unknown0")).Dial(context.Background(), l.Addr().String())
+	ass.Error(err)
+
+	if _, err := net.InterfaceByName("lo"); err == nil {
+		cn, err = Chain(&TCPDialer{}, WithInterface("lo")).Dial(context.Background(), l.Addr().String())
+		ass.NoError(err)
+		ass.Equal("127.0.0.1", <-remotes)
+		ass.NoError(cn.Close())
+	}
+}
@@ -0,0 +1,102 @@
+package goconnpool
+
+import "sync/atomic"
+
+// sharedConn is the connection leased to several borrowers concurrently (see Config.MaxStreamsPerConn).
+type sharedConn[T any] struct {
+	cn     T
+	id     uint64
+	leases int
+
+	// closing is set when the connection shouldn't be leased anymore:
+	// the connection is closed when all leases are released.
+	closing bool
+}
+
+// findSharedConn returns the least loaded connection with free streams or nil.
+func (s *server[T]) findSharedConn() *sharedConn[T] {
+	// XXX: Function should be called under mutex
+
+	var found *sharedConn[T]
+	for _, sc := range s.sharedConns {
+		if sc.closing || sc.leases >= s.maxStreams {
+			continue
+		}
+
+		if found == nil || sc.leases < found.leases {
+			found = sc
+		}
+	}
+
+	if found == nil || found.leases > 0 {
+		return found
+	}
+
+	if err := s.factory.Validate(found.cn); err != nil {
+		s.log.debug("idle connection is invalid", serverField(s.addr), connIDField(found.id), errorField(err))
+		s.closeSharedConn(found) // nolint:errcheck
+		return s.findSharedConn()
+	}
+
+	return found
+}
+
+func (s *server[T]) hasFreeStreams() bool {
+	// XXX: Function should be called under mutex
+
+	for _, sc := range s.sharedConns {
+		if !sc.closing && sc.leases < s.maxStreams {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lease borrows one stream of the connection.
+// Each lease has its own id: leases are tracked (and reported in stats) like exclusively borrowed connections.
+func (s *server[T]) lease(sc *sharedConn[T]) *resource[T] {
+	// XXX: Function should be called under mutex
+
+	sc.leases++
+	r := s.borrow(sc.cn, atomic.AddUint64(&lastConnID, 1))
+	r.shared = sc
+
+	s.log.debug("connection leased", serverField(s.addr), connIDField(sc.id))
+	return r
+}
+
+// release releases one lease of the connection.
+// The connection is closed when it is broken (or server is drained) and there is no more leases.
+func (s *server[T]) release(sc *sharedConn[T], broken bool) error {
+	// XXX: Function should be called under mutex
+
+	sc.leases--
+	if broken || s.mode == ServerModeDrain {
+		sc.closing = true
+	}
+
+	if !broken {
+		s.hooks.release(s.addr)
+	}
+
+	if sc.leases > 0 || !sc.closing {
+		s.log.debug("connection lease released", serverField(s.addr), connIDField(sc.id))
+		return nil
+	}
+
+	return s.closeSharedConn(sc)
+}
+
+func (s *server[T]) closeSharedConn(sc *sharedConn[T]) error {
+	// XXX: Function should be called under mutex
+
+	for i := range s.sharedConns {
+		if s.sharedConns[i] == sc {
+			s.sharedConns = append(s.sharedConns[:i], s.sharedConns[i+1:]...)
+			break
+		}
+	}
+
+	return s.closeConn(sc.cn, sc.id)
+}
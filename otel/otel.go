@@ -0,0 +1,279 @@
+// Package otel implements OpenTelemetry tracing of goconnpool.ConnPool.
+//
+// Each OpenConn* and Do* call of the wrapped pool creates a span (as a child of the span stored in the passed
+// context). Each dial of the wrapped dialer creates a child span of this span.
+//
+//	cfg.Dialer = otel.WrapDialer(cfg.Dialer)
+//	pool := otel.WrapPool(goconnpool.NewConnPool(cfg))
+package otel
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/derElektrobesen/goconnpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/derElektrobesen/goconnpool/otel"
+
+// Attributes set by the package.
+const (
+	// ServerKey is the address of the server the connection was opened (or dialed) to.
+	ServerKey = attribute.Key("goconnpool.server")
+
+	// ReusedKey is true if the connection was taken from the opened connections of the pool.
+	// It is set only once dials of the pool are traced (see WrapDialer): reuse can't be told apart otherwise.
+	ReusedKey = attribute.Key("goconnpool.conn.reused")
+
+	// DialAttemptsKey is the number of dials made during OpenConn* call.
+	DialAttemptsKey = attribute.Key("goconnpool.dial.attempts")
+
+	// DialFailuresKey is the number of failed dials made during OpenConn* call.
+	DialFailuresKey = attribute.Key("goconnpool.dial.failures")
+)
+
+type config struct {
+	tp trace.TracerProvider
+}
+
+// Option configures the wrappers.
+type Option func(*config)
+
+// WithTracerProvider sets the TracerProvider used to create spans.
+// Global TracerProvider is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tp = tp
+	}
+}
+
+func newTracer(opts []Option) trace.Tracer {
+	var c config
+	for _, o := range opts {
+		o(&c)
+	}
+
+	if c.tp == nil {
+		c.tp = otel.GetTracerProvider()
+	}
+
+	return c.tp.Tracer(instrumentationName)
+}
+
+// dialState collects dials made during one OpenConn* (or Do*) call.
+// Dials could be finished after OpenConn* return (in case of connect timeout): mutex is required.
+type dialState struct {
+	mu       sync.Mutex
+	attempts int
+	failures int
+	cn       goconnpool.Conn // the last connection passed into Do* callback
+}
+
+type dialStateKey struct{}
+
+func (st *dialState) add(err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.attempts++
+	if err != nil {
+		st.failures++
+	}
+}
+
+func (st *dialState) setConn(cn goconnpool.Conn) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.cn = cn
+}
+
+func (st *dialState) conn() goconnpool.Conn {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return st.cn
+}
+
+func (st *dialState) attributes() (attempts, failures int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return st.attempts, st.failures
+}
+
+// WrapPool returns the pool which traces OpenConn* and Do* calls.
+// Spans of Do* calls have the server of the last connection passed into the callback.
+// Use WrapDialer to trace dials made by the pool.
+func WrapPool(p goconnpool.ConnPool, opts ...Option) goconnpool.ConnPool {
+	return &pool{
+		ConnPool: p,
+		tracer:   newTracer(opts),
+	}
+}
+
+type pool struct {
+	goconnpool.ConnPool
+	tracer trace.Tracer
+
+	// dialsTraced is set once any dial was recorded: the dialer of the pool is wrapped with WrapDialer.
+	dialsTraced atomic.Bool
+}
+
+func (p *pool) start(ctx context.Context, name string) (context.Context, trace.Span, *dialState) {
+	st := &dialState{}
+	ctx, span := p.tracer.Start(ctx, name)
+	return context.WithValue(ctx, dialStateKey{}, st), span, st
+}
+
+func (p *pool) end(span trace.Span, st *dialState, cn goconnpool.Conn, err error) {
+	defer span.End()
+
+	attempts, failures := st.attributes()
+	span.SetAttributes(
+		DialAttemptsKey.Int(attempts),
+		DialFailuresKey.Int(failures),
+	)
+
+	if attempts > 0 {
+		p.dialsTraced.Store(true)
+	}
+
+	if cn != nil {
+		span.SetAttributes(ServerKey.String(cn.ServerAddr()))
+		if p.dialsTraced.Load() {
+			// successfully dialed connection is always returned to the user
+			span.SetAttributes(ReusedKey.Bool(attempts == failures))
+		}
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+func (p *pool) OpenConnNonBlock(ctx context.Context) (goconnpool.Conn, error) {
+	ctx, span, st := p.start(ctx, "goconnpool.OpenConnNonBlock")
+	cn, err := p.ConnPool.OpenConnNonBlock(ctx)
+	p.end(span, st, cn, err)
+	return cn, err
+}
+
+func (p *pool) OpenConn(ctx context.Context) (goconnpool.Conn, error) {
+	ctx, span, st := p.start(ctx, "goconnpool.OpenConn")
+	cn, err := p.ConnPool.OpenConn(ctx)
+	p.end(span, st, cn, err)
+	return cn, err
+}
+
+func (p *pool) OpenConnWithTimeout(ctx context.Context, timeout time.Duration) (goconnpool.Conn, error) {
+	ctx, span, st := p.start(ctx, "goconnpool.OpenConnWithTimeout")
+	cn, err := p.ConnPool.OpenConnWithTimeout(ctx, timeout)
+	p.end(span, st, cn, err)
+	return cn, err
+}
+
+func (p *pool) OpenConnToServer(ctx context.Context, addr string) (goconnpool.Conn, error) {
+	ctx, span, st := p.start(ctx, "goconnpool.OpenConnToServer")
+	cn, err := p.ConnPool.OpenConnToServer(ctx, addr)
+	p.end(span, st, cn, err)
+	return cn, err
+}
+
+func (p *pool) OpenConnToServerBlock(ctx context.Context, addr string) (goconnpool.Conn, error) {
+	ctx, span, st := p.start(ctx, "goconnpool.OpenConnToServerBlock")
+	cn, err := p.ConnPool.OpenConnToServerBlock(ctx, addr)
+	p.end(span, st, cn, err)
+	return cn, err
+}
+
+func (p *pool) Do(ctx context.Context, fn func(goconnpool.Conn) error) error {
+	ctx, span, st := p.start(ctx, "goconnpool.Do")
+	err := p.ConnPool.Do(ctx, func(cn goconnpool.Conn) error {
+		st.setConn(cn)
+		return fn(cn)
+	})
+	p.end(span, st, st.conn(), err)
+	return err
+}
+
+func (p *pool) DoWithRetry(ctx context.Context, policy goconnpool.RetryPolicy, fn func(goconnpool.Conn) error) error {
+	ctx, span, st := p.start(ctx, "goconnpool.DoWithRetry")
+	err := p.ConnPool.DoWithRetry(ctx, policy, func(cn goconnpool.Conn) error {
+		st.setConn(cn)
+		return fn(cn)
+	})
+	p.end(span, st, st.conn(), err)
+	return err
+}
+
+func (p *pool) DoHedged(ctx context.Context, hedgeDelay time.Duration, maxHedges int,
+	fn func(context.Context, goconnpool.Conn) error) error {
+
+	ctx, span, st := p.start(ctx, "goconnpool.DoHedged")
+	err := p.ConnPool.DoHedged(ctx, hedgeDelay, maxHedges, func(ctx context.Context, cn goconnpool.Conn) error {
+		st.setConn(cn)
+		return fn(ctx, cn)
+	})
+	p.end(span, st, st.conn(), err)
+	return err
+}
+
+// WrapDialer returns the dialer which creates the span for each dial.
+// The pool default dialer is used if d is nil (see goconnpool.DefaultDialer): pass goconnpool.DefaultDialer(cfg)
+// explicitly to use the pool clock. Address validation (see goconnpool.AddressValidator) is delegated to d.
+func WrapDialer(d goconnpool.Dialer, opts ...Option) goconnpool.Dialer {
+	if d == nil {
+		d = goconnpool.DefaultDialer(goconnpool.Config{})
+	}
+
+	wrapped := &dialer{
+		Dialer: d,
+		tracer: newTracer(opts),
+	}
+
+	if v, ok := d.(goconnpool.AddressValidator); ok {
+		return validatingDialer{Dialer: wrapped, v: v}
+	}
+
+	return wrapped
+}
+
+type validatingDialer struct {
+	goconnpool.Dialer
+	v goconnpool.AddressValidator
+}
+
+func (d validatingDialer) ValidateAddress(address string) error {
+	return d.v.ValidateAddress(address)
+}
+
+type dialer struct {
+	goconnpool.Dialer
+	tracer trace.Tracer
+}
+
+func (d *dialer) Dial(ctx context.Context, address string) (net.Conn, error) {
+	ctx, span := d.tracer.Start(ctx, "goconnpool.Dial", trace.WithAttributes(ServerKey.String(address)))
+	defer span.End()
+
+	cn, err := d.Dialer.Dial(ctx, address)
+	if st, ok := ctx.Value(dialStateKey{}).(*dialState); ok {
+		st.add(err)
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return cn, err
+}
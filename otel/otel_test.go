@@ -0,0 +1,205 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/derElektrobesen/goconnpool"
+	"github.com/derElektrobesen/goconnpool/internal/pooltest"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	p := WrapPool(goconnpool.NewConnPool(goconnpool.Config{
+		MaxRPS: math.MaxInt32,
+		Dialer: WrapDialer(pooltest.Dialer{}, WithTracerProvider(tp)),
+	}), WithTracerProvider(tp))
+
+	pooltest.RegisterServers(t, p)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+
+	cn, err := p.OpenConn(ctx) // "ok" is dialed
+	ass.NoError(err)
+	ass.NoError(cn.ReturnToPool())
+
+	_, err = p.OpenConnNonBlock(ctx) // "bad" is down, "ok" is reused
+	ass.NoError(err)
+
+	_, err = p.OpenConnNonBlock(ctx) // "bad" waits for backoff, "ok" has no free conns
+	ass.Error(err)
+
+	parent.End()
+
+	type span struct {
+		name   string
+		parent string
+		status codes.Code
+		attrs  []attribute.KeyValue
+	}
+
+	names := map[string]string{}
+	var got []span
+	for _, s := range sr.Ended() {
+		names[s.SpanContext().SpanID().String()] = s.Name()
+	}
+
+	for _, s := range sr.Ended() {
+		got = append(got, span{
+			name:   s.Name(),
+			parent: names[s.Parent().SpanID().String()],
+			status: s.Status().Code,
+			attrs:  s.Attributes(),
+		})
+	}
+
+	ass.Equal([]span{
+		{
+			name:   "goconnpool.Dial",
+			parent: "goconnpool.OpenConn",
+			attrs:  []attribute.KeyValue{ServerKey.String("ok")},
+		},
+		{
+			name:   "goconnpool.OpenConn",
+			parent: "parent",
+			attrs: []attribute.KeyValue{
+				DialAttemptsKey.Int(1),
+				DialFailuresKey.Int(0),
+				ServerKey.String("ok"),
+				ReusedKey.Bool(false),
+			},
+		},
+		{
+			name:   "goconnpool.Dial",
+			parent: "goconnpool.OpenConnNonBlock",
+			status: codes.Error,
+			attrs:  []attribute.KeyValue{ServerKey.String("bad")},
+		},
+		{
+			name:   "goconnpool.OpenConnNonBlock",
+			parent: "parent",
+			attrs: []attribute.KeyValue{
+				DialAttemptsKey.Int(1),
+				DialFailuresKey.Int(1),
+				ServerKey.String("ok"),
+				ReusedKey.Bool(true),
+			},
+		},
+		{
+			name:   "goconnpool.OpenConnNonBlock",
+			parent: "parent",
+			status: codes.Error,
+			attrs: []attribute.KeyValue{
+				DialAttemptsKey.Int(0),
+				DialFailuresKey.Int(0),
+			},
+		},
+		{
+			name: "parent",
+		},
+	}, got)
+}
+
+func TestWrapDialerValidation(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	p := goconnpool.NewConnPool(goconnpool.Config{
+		Dialer: WrapDialer(nil),
+	})
+
+	// addresses are validated by the wrapped dialer
+	ass.NoError(p.RegisterServer("127.0.0.1:80"))
+	ass.Error(p.RegisterServer("udp://127.0.0.1:80"))
+
+	_, ok := WrapDialer(pooltest.Dialer{}).(goconnpool.AddressValidator)
+	ass.False(ok)
+}
+
+func TestTracingDo(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	p := WrapPool(goconnpool.NewConnPool(goconnpool.Config{
+		MaxRPS: math.MaxInt32,
+		Dialer: WrapDialer(pooltest.Dialer{}, WithTracerProvider(tp)),
+	}), WithTracerProvider(tp))
+
+	pooltest.RegisterServers(t, p, pooltest.OK)
+
+	ass.NoError(p.Do(context.Background(), func(goconnpool.Conn) error { return nil }))
+	ass.Error(p.DoWithRetry(context.Background(), goconnpool.RetryPolicy{MaxAttempts: 1},
+		func(goconnpool.Conn) error { return fmt.Errorf("request failed") }))
+
+	spans := sr.Ended()
+	ass.Len(spans, 3)
+
+	ass.Equal("goconnpool.Dial", spans[0].Name())
+	ass.Equal("goconnpool.Do", spans[1].Name())
+	ass.Equal(spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	ass.Equal([]attribute.KeyValue{
+		DialAttemptsKey.Int(1),
+		DialFailuresKey.Int(0),
+		ServerKey.String(pooltest.OK),
+		ReusedKey.Bool(false),
+	}, spans[1].Attributes())
+
+	ass.Equal("goconnpool.DoWithRetry", spans[2].Name())
+	ass.Equal(codes.Error, spans[2].Status().Code)
+	ass.Equal([]attribute.KeyValue{
+		DialAttemptsKey.Int(0),
+		DialFailuresKey.Int(0),
+		ServerKey.String(pooltest.OK),
+		ReusedKey.Bool(true),
+	}, spans[2].Attributes())
+}
+
+func TestTracingUnwrappedDialer(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	p := WrapPool(goconnpool.NewConnPool(goconnpool.Config{
+		MaxRPS: math.MaxInt32,
+		Dialer: pooltest.Dialer{},
+	}), WithTracerProvider(tp))
+
+	pooltest.RegisterServers(t, p, pooltest.OK)
+
+	cn, err := p.OpenConn(context.Background())
+	ass.NoError(err)
+	ass.NoError(cn.ReturnToPool())
+
+	cn, err = p.OpenConn(context.Background())
+	ass.NoError(err)
+	ass.NoError(cn.ReturnToPool())
+
+	spans := sr.Ended()
+	ass.Len(spans, 2)
+
+	// dials aren't traced: reuse is unknown
+	for _, s := range spans {
+		ass.Equal([]attribute.KeyValue{
+			DialAttemptsKey.Int(0),
+			DialFailuresKey.Int(0),
+			ServerKey.String(pooltest.OK),
+		}, s.Attributes())
+	}
+}
@@ -0,0 +1,106 @@
+package goconnpool
+
+import (
+	"context"
+	"time"
+)
+
+// Factory creates and destroys resources of the specific type (connections, clients, sessions, etc)
+// pooled by Pool.
+type Factory[T any] interface {
+	// Create creates new resource connected to the address passed (this address is one of the registered
+	// servers addresses).
+	//
+	// Function should be implemented in the thread-safe way.
+	// Connect timeout is setuped before this function invocation: be sure your function not stuck in the case of
+	// timeout.
+	Create(ctx context.Context, addr string) (T, error)
+
+	// Close destroys the resource which couldn't be reused anymore.
+	Close(x T) error
+
+	// Validate is called before the idle resource is reused.
+	// Resource is closed (and another one is taken) if error is returned.
+	Validate(x T) error
+}
+
+// Resource is a wrapper around the pooled resource.
+type Resource[T any] interface {
+	// Value returns the resource returned by the factory.
+	//
+	// XXX: Returned resource shouldn't be closed.
+	Value() T
+
+	// ReturnToPool returns resource back into pool.
+	// This method should be called to reuse already created resource.
+	//
+	// To prevent resources leaking eigther Close() or ReturnToPool() methods should be called.
+	//
+	// Resource shouldn't be used after returning to pool (or after Close call).
+	ReturnToPool() error
+
+	// Close closes the resource using Factory.Close.
+	Close() error
+
+	// ServerAddr returns the address of the registered server the resource was created for.
+	// This address is the same address used into RegisterServer call.
+	ServerAddr() string
+}
+
+// Pool is the generic pool of resources of any type (like gRPC clients, thrift clients, SSH sessions).
+// Ratelimits, backoff and servers balancing are the same as ConnPool has (ConnPool is the Pool of net.Conn).
+// See ConnPool for methods documentation.
+//
+// Config.Dialer isn't used by Pool: resources are created by the Factory.
+type Pool[T any] interface {
+	// GetNonBlock requests one resource from the pool (like ConnPool.OpenConnNonBlock does).
+	GetNonBlock(ctx context.Context) (Resource[T], error)
+
+	// Get does same things as GetNonBlock, but it blocks until new resource will be created
+	// (like ConnPool.OpenConn does).
+	Get(ctx context.Context) (Resource[T], error)
+
+	// GetWithTimeout does same things as Get, but it stops to wait new resource after timeout.
+	GetWithTimeout(ctx context.Context, timeout time.Duration) (Resource[T], error)
+
+	// GetFromServer requests one resource created for the specific server
+	// (like ConnPool.OpenConnToServer does).
+	GetFromServer(ctx context.Context, addr string) (Resource[T], error)
+
+	// GetFromServerBlock does same things as GetFromServer, but it waits while the server is ratelimited
+	// (like ConnPool.OpenConnToServerBlock does).
+	GetFromServerBlock(ctx context.Context, addr string) (Resource[T], error)
+
+	// Do borrows the resource and passes it into fn (see ConnPool.Do).
+	Do(ctx context.Context, fn func(T) error) error
+
+	// DoWithRetry does same things as Do, but fn is retried according to the policy passed
+	// (see ConnPool.DoWithRetry).
+	DoWithRetry(ctx context.Context, policy RetryPolicy, fn func(T) error) error
+
+	// DoHedged does same things as Do, but hedged requests are sent (see ConnPool.DoHedged).
+	DoHedged(ctx context.Context, hedgeDelay time.Duration, maxHedges int, fn func(context.Context, T) error) error
+
+	// RegisterServer registers new server in the pool (see ConnPool.RegisterServer).
+	// Error is returned if the factory implements AddressValidator and the address is invalid
+	// or if the effective config of the server is invalid.
+	//
+	// This operation is a part of initialization.
+	// Don't try to call it in runtime: not thread safe.
+	RegisterServer(addr string, opts ...ServerOption) error
+
+	// UpdateConfig applies new limits, timeouts and backoff intervals to the registered servers
+	// (see ConnPool.UpdateConfig). Dialer and ConnWrapper are ignored: factory isn't changed.
+	UpdateConfig(cfg Config) error
+
+	// Stats returns the snapshot of the registered servers state.
+	Stats() Stats
+
+	// SetServerMode changes the mode of the registered server.
+	SetServerMode(addr string, mode ServerMode) error
+}
+
+// NewPool creates new pool of resources created by the factory passed.
+func NewPool[T any](cfg Config, f Factory[T]) Pool[T] {
+	return newPool(cfg, f)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -11,30 +12,79 @@ import (
 
 var (
 	ErrNoServersRegistered = fmt.Errorf("no registered servers found")
+	ErrUnknownServer       = fmt.Errorf("unknown server")
+
+	// errAllRatelimited is returned by openConn when all servers are alive but ratelimited:
+	// waiting for them doesn't add the load, so it isn't counted as a retry.
+	errAllRatelimited = fmt.Errorf("all servers are ratelimited")
 )
 
-type connPool struct {
-	cfg Config
+type pool[T any] struct {
+	// XXX: cfg isn't changed after construction: it is read without p.mu.
+	// Server-level settings changed by UpdateConfig are kept in serversCfg.
+	cfg        Config
+	serversCfg atomic.Pointer[Config]
+	log        logger
+	budget     *retryBudget
 
 	mu sync.Mutex
 
 	servers             roundRobin
-	connProviderFactory func(addr string, cfg Config) connectionProvider
+	serversByAddr       map[string]connectionProvider[T]
+	factory             Factory[T]
+	serverOpts          map[string][]ServerOption
+	connProviderFactory func(addr string, cfg Config) connectionProvider[T]
 }
 
-func newConnPool(cfg Config) *connPool {
+func newPool[T any](cfg Config, f Factory[T]) *pool[T] {
 	cfg = cfg.withDefaults()
 
-	return &connPool{
-		cfg:                 cfg,
-		connProviderFactory: newServerWrapper, // required for tests
+	p := &pool[T]{
+		cfg:           cfg,
+		log:           logger{l: cfg.Logger},
+		budget:        newRetryBudget(cfg.RetryBudget),
+		serversByAddr: map[string]connectionProvider[T]{},
+		factory:       f,
+		serverOpts:    map[string][]ServerOption{},
+
+		// required for tests
+		connProviderFactory: func(addr string, cfg Config) connectionProvider[T] {
+			return newServer(addr, cfg, f)
+		},
 	}
+
+	p.serversCfg.Store(&cfg)
+
+	return p
 }
 
-func (p *connPool) OpenConn(ctx context.Context) (Conn, error) {
+func (p *pool[T]) Get(ctx context.Context) (Resource[T], error) {
+	r, err := p.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (p *pool[T]) get(ctx context.Context) (*resource[T], error) {
+	r, err := p.openConnBlock(ctx, nil)
+	if err == nil {
+		p.budget.deposit()
+	}
+
+	return r, err
+}
+
+// openConnBlock waits for the connection like Get does.
+// Servers from exclude set are used only if other servers can't return the connection.
+func (p *pool[T]) openConnBlock(ctx context.Context, exclude map[connectionProvider[T]]bool) (*resource[T], error) {
+	started := p.cfg.Clock.Now()
+
 	for {
-		cn, timeout, err := p.openConn(ctx)
+		cn, timeout, err := p.openConn(ctx, exclude, true)
 		if err == nil {
+			p.cfg.Hooks.acquire(cn, p.cfg.Clock.Since(started))
 			return cn, nil
 		}
 
@@ -42,7 +92,13 @@ func (p *connPool) OpenConn(ctx context.Context) (Conn, error) {
 			return nil, err
 		}
 
-		p.cfg.Logger.Infof("can't connect to servers: %s; retry after %s", err, timeout)
+		// only re-attempts after server failures are retries: waits for ratelimits don't add the load
+		if err != errAllRatelimited && !p.budget.withdraw() {
+			p.log.info("can't connect to servers: retry budget exhausted", errorField(err))
+			return nil, errors.Wrap(ErrRetryBudgetExhausted, err.Error())
+		}
+
+		p.log.info("can't connect to servers", errorField(err), retryAfterField(timeout))
 
 		select {
 		case <-ctx.Done():
@@ -52,18 +108,91 @@ func (p *connPool) OpenConn(ctx context.Context) (Conn, error) {
 	}
 }
 
-func (p *connPool) OpenConnWithTimeout(ctx context.Context, timeout time.Duration) (Conn, error) {
+func (p *pool[T]) GetWithTimeout(ctx context.Context, timeout time.Duration) (Resource[T], error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	return p.OpenConn(ctx)
+	return p.Get(ctx)
 }
 
-func (p *connPool) OpenConnNonBlock(ctx context.Context) (Conn, error) {
-	cn, _, err := p.openConn(ctx)
-	return cn, err
+func (p *pool[T]) GetNonBlock(ctx context.Context) (Resource[T], error) {
+	r, err := p.getNonBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (p *pool[T]) getNonBlock(ctx context.Context) (*resource[T], error) {
+	started := p.cfg.Clock.Now()
+
+	r, _, err := p.openConn(ctx, nil, false)
+	if err == nil {
+		p.cfg.Hooks.acquire(r, p.cfg.Clock.Since(started))
+		p.budget.deposit()
+	}
+
+	return r, err
+}
+
+func (p *pool[T]) GetFromServer(ctx context.Context, addr string) (Resource[T], error) {
+	r, err := p.getFromServer(ctx, addr, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
 }
 
-func (p *connPool) openConn(ctx context.Context) (Conn, time.Duration, error) {
+func (p *pool[T]) GetFromServerBlock(ctx context.Context, addr string) (Resource[T], error) {
+	r, err := p.getFromServer(ctx, addr, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// getFromServer returns the connection to the server with address passed.
+// If block is set, ratelimits of the server are waited for like openConnBlock does.
+func (p *pool[T]) getFromServer(ctx context.Context, addr string, block bool) (*resource[T], error) {
+	// XXX: serversByAddr is modified only during initialization: p.mu isn't required
+	s, ok := p.serversByAddr[addr]
+	if !ok {
+		return nil, errors.Wrap(ErrUnknownServer, addr)
+	}
+
+	started := p.cfg.Clock.Now()
+
+	for {
+		r, err := s.getConnection(ctx)
+		if err == nil {
+			p.cfg.Hooks.acquire(r, p.cfg.Clock.Since(started))
+			p.budget.deposit()
+			return r, nil
+		}
+
+		// waits for ratelimits don't add the load: the retry budget isn't used here
+		if !block || errors.Cause(err) != errRatelimit {
+			return nil, err
+		}
+
+		timeout := s.retryTimeout()
+		p.log.info("can't connect to server", errorField(err), retryAfterField(timeout))
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("operation cancelled")
+		case <-p.cfg.Clock.After(timeout):
+		}
+	}
+}
+
+// openConn returns the connection to the first available server in round-robin order.
+// Servers from exclude set are skipped: they are tried after all other servers only if fallback is set.
+func (p *pool[T]) openConn(ctx context.Context, exclude map[connectionProvider[T]]bool,
+	fallback bool) (*resource[T], time.Duration, error) {
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -78,8 +207,22 @@ func (p *connPool) openConn(ctx context.Context) (Conn, time.Duration, error) {
 		maxTimeout     time.Duration
 	)
 
-	for i := 0; i < p.servers.size(); i++ {
-		s := p.servers.next().(connectionProvider)
+	var excluded []connectionProvider[T]
+
+	// excluded servers are tried after all other servers
+	for i := 0; i < p.servers.size()+len(excluded); i++ {
+		var s connectionProvider[T]
+		if i < p.servers.size() {
+			s = p.servers.next().(connectionProvider[T])
+			if exclude[s] {
+				if fallback {
+					excluded = append(excluded, s)
+				}
+				continue
+			}
+		} else {
+			s = excluded[i-p.servers.size()]
+		}
 
 		cn, err := s.getConnection(ctx)
 		if err == nil {
@@ -88,12 +231,12 @@ func (p *connPool) openConn(ctx context.Context) (Conn, time.Duration, error) {
 
 		switch errors.Cause(err) {
 		case errServerIsDown:
-			p.cfg.Logger.Errorf("can't connect to server: %s", err)
+			// error is already logged by the server
 			hasDown = true
 		case errRatelimit:
 			hasRatelimited = true
 		default:
-			p.cfg.Logger.Errorf("can't connect to server: %s", err)
+			p.log.error("can't connect to server", errorField(err))
 			globErr = err
 		}
 
@@ -108,12 +251,133 @@ func (p *connPool) openConn(ctx context.Context) (Conn, time.Duration, error) {
 	} else if hasDown {
 		globErr = errors.New("all servers are down")
 	} else if hasRatelimited {
-		globErr = errors.New("all servers are ratelimited")
+		globErr = errAllRatelimited
+	} else if globErr == nil {
+		globErr = errors.New("all servers are excluded")
 	}
 
 	return nil, maxTimeout, globErr
 }
 
-func (p *connPool) RegisterServer(addr string) {
-	p.servers.push(p.connProviderFactory(addr, p.cfg))
+func (p *pool[T]) RegisterServer(addr string, opts ...ServerOption) error {
+	if v, ok := p.factory.(AddressValidator); ok {
+		if err := v.ValidateAddress(addr); err != nil {
+			return errors.Wrapf(err, "can't register server %s", addr)
+		}
+	}
+
+	cfg, err := serverConfig(p.serversConfig(), opts)
+	if err != nil {
+		return errors.Wrapf(err, "can't register server %s", addr)
+	}
+
+	s := p.connProviderFactory(addr, cfg)
+	p.servers.push(s)
+	p.serversByAddr[addr] = s
+	p.serverOpts[addr] = opts
+
+	return nil
+}
+
+func (p *pool[T]) UpdateConfig(cfg Config) error {
+	return p.updateConfig(cfg, nil)
+}
+
+// updateConfig applies server-level settings of the config passed to all servers.
+// Factory isn't changed if f is nil. Nothing is changed if some server config is invalid.
+func (p *pool[T]) updateConfig(cfg Config, f Factory[T]) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	cfg = cfg.withDefaults()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// XXX: p.mu serializes updates only: serversCfg is read without it
+	newCfg := p.serversConfig()
+	newCfg.MaxConnsPerServer = cfg.MaxConnsPerServer
+	newCfg.MaxStreamsPerConn = cfg.MaxStreamsPerConn
+	newCfg.MaxRPS = cfg.MaxRPS
+	newCfg.ConnectTimeout = cfg.ConnectTimeout
+	newCfg.InitialBackoffInterval = cfg.InitialBackoffInterval
+	newCfg.MaxBackoffInterval = cfg.MaxBackoffInterval
+
+	if f != nil {
+		newCfg.Dialer = cfg.Dialer
+		newCfg.ConnWrapper = cfg.ConnWrapper
+	}
+
+	scfgs := make(map[string]Config, len(p.serversByAddr))
+	for addr := range p.serversByAddr {
+		scfg, err := serverConfig(newCfg, p.serverOpts[addr])
+		if err != nil {
+			return errors.Wrapf(err, "server %s", addr)
+		}
+
+		scfgs[addr] = scfg
+	}
+
+	p.serversCfg.Store(&newCfg)
+	if f != nil {
+		p.factory = f
+	}
+
+	for addr, s := range p.serversByAddr {
+		s.updateConfig(scfgs[addr], f)
+	}
+
+	p.log.info("config updated")
+
+	return nil
+}
+
+// serversConfig returns the pool config with server-level settings applied by the last UpdateConfig call.
+func (p *pool[T]) serversConfig() Config {
+	return *p.serversCfg.Load()
+}
+
+// serverConfig returns the effective config of the server: cfg with server options applied.
+func serverConfig(cfg Config, opts []ServerOption) (Config, error) {
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func (p *pool[T]) Stats() Stats {
+	// XXX: p.mu isn't locked here: servers list is modified only during initialization,
+	// but p.mu is held during whole openConn call (including dials).
+	st := Stats{
+		Servers: make([]ServerStats, 0, p.servers.size()),
+	}
+
+	for _, s := range p.servers.data {
+		st.Servers = append(st.Servers, s.(connectionProvider[T]).stats())
+	}
+
+	return st
+}
+
+func (p *pool[T]) SetServerMode(addr string, mode ServerMode) error {
+	switch mode {
+	case ServerModeAuto, ServerModeDown, ServerModeDrain:
+	default:
+		return errors.Errorf("unknown server mode %q", mode)
+	}
+
+	// XXX: serversByAddr is modified only during initialization: p.mu isn't required
+	s, ok := p.serversByAddr[addr]
+	if !ok {
+		return errors.Wrap(ErrUnknownServer, addr)
+	}
+
+	s.setMode(mode)
+	return nil
 }
@@ -4,13 +4,17 @@ import (
 	context "context"
 	"flag"
 	"fmt"
+	"math"
+	"net"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/benbjohnson/clock"
-	gomock "github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
 )
 
 func testConfigParsing(t *testing.T) {
@@ -21,6 +25,7 @@ func testConfigParsing(t *testing.T) {
 	require.NoError(t, s.Parse(
 		strings.Split(
 			"-max_conns_per_server 10 "+
+				"-max_streams_per_conn 4 "+
 				"-max_rps 20 "+
 				"-connect_timeout 25ms "+
 				"-init_backoff_interval 18s "+
@@ -32,12 +37,19 @@ func testConfigParsing(t *testing.T) {
 	require.Equal(t,
 		Config{
 			MaxConnsPerServer:      10,
+			MaxStreamsPerConn:      4,
 			MaxRPS:                 20,
 			ConnectTimeout:         25 * time.Millisecond,
 			InitialBackoffInterval: 18 * time.Second,
 			MaxBackoffInterval:     46 * time.Minute,
 		},
 		*cfgPtr)
+
+	s = flag.NewFlagSet("test", flag.PanicOnError)
+	cfgPtr = NewConfig(s)
+	require.NoError(t, s.Parse(nil))
+	require.Equal(t, DefaultConnectTimeout, cfgPtr.ConnectTimeout)
+	require.NoError(t, cfgPtr.Validate())
 }
 
 func testConfigFillDefaults(t *testing.T) {
@@ -47,14 +59,20 @@ func testConfigFillDefaults(t *testing.T) {
 	require.Equal(t,
 		Config{
 			MaxConnsPerServer:      DefaultMaxConnsPerServer,
+			MaxStreamsPerConn:      DefaultMaxStreamsPerConn,
 			MaxRPS:                 DefaultMaxRPS,
 			ConnectTimeout:         DefaultConnectTimeout,
 			InitialBackoffInterval: DefaultInitBackoffInterval,
 			MaxBackoffInterval:     DefaultMaxBackoffInterval,
 			Clock:                  SystemClock{},
 			Logger:                 DummyLogger{},
-			Dialer:                 &TCPDialer{},
+			Dialer: &HappyEyeballsDialer{
+				InitialBackoffInterval: DefaultInitBackoffInterval,
+				MaxBackoffInterval:     DefaultMaxBackoffInterval,
+				Clock:                  SystemClock{},
+			},
 		}, s.cfg)
+	require.Equal(t, s.cfg.Dialer, DefaultDialer(Config{}))
 
 	// just to increment code coverage: nothing to test
 	s.cfg.Logger.Errorf(">>>")
@@ -69,16 +87,56 @@ func testConfigFillDefaults(t *testing.T) {
 	s.cfg.Dialer.Dial(ctx, "127.0.0.1:95328") // nolint:errcheck
 }
 
+func testConfigValidation(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	ass.NoError(Config{}.Validate())
+	ass.NoError(Config{MaxRPS: math.MaxInt32, InitialBackoffInterval: time.Second}.Validate())
+
+	for msg, cfg := range map[string]Config{
+		"MaxConnsPerServer should not be negative (got -1)": {MaxConnsPerServer: -1},
+		"MaxStreamsPerConn should not be negative (got -2)": {MaxStreamsPerConn: -2},
+		"MaxRPS should not be negative (got -3)":            {MaxRPS: -3},
+		"ConnectTimeout should not be negative (got -1s)":   {ConnectTimeout: -time.Second},
+		"InitialBackoffInterval should not be negative (got -1ms)": {
+			InitialBackoffInterval: -time.Millisecond,
+		},
+		"MaxBackoffInterval should not be negative (got -1m0s)": {MaxBackoffInterval: -time.Minute},
+		"LeakThreshold should not be negative (got -1ns)":       {LeakThreshold: -1},
+		"MaxBackoffInterval (1s) should not be less than InitialBackoffInterval (2s)": {
+			InitialBackoffInterval: 2 * time.Second,
+			MaxBackoffInterval:     time.Second,
+		},
+		"MaxBackoffInterval (30s) should not be less than InitialBackoffInterval (1m0s)": {
+			InitialBackoffInterval: time.Minute,
+		},
+	} {
+		err := cfg.Validate()
+		ass.EqualError(err, "invalid config: "+msg)
+
+		p, err := NewConnPoolE(cfg)
+		ass.Nil(p)
+		ass.EqualError(err, "invalid config: "+msg)
+	}
+
+	p, err := NewConnPoolE(Config{MaxRPS: 10})
+	ass.NoError(err)
+	ass.NotNil(p)
+}
+
 func testDefaultConnPoolCreation(t *testing.T) {
 	// this test also exists to increase code coverage: nothing to test here %)
 	t.Parallel()
 
 	s := NewConnPool(Config{})
-	s.RegisterServer("y")
+	require.NoError(t, s.RegisterServer("y"))
 }
 
-func newTestConnProviderFactory(srvs ...connectionProvider) func(addr string, cfg Config) connectionProvider {
-	return func(addr string, cfg Config) connectionProvider {
+func newTestConnProviderFactory(
+	srvs ...connectionProvider[net.Conn],
+) func(addr string, cfg Config) connectionProvider[net.Conn] {
+	return func(addr string, cfg Config) connectionProvider[net.Conn] {
 		if len(srvs) == 0 {
 			panic("unexpected call of conn provider factory")
 		}
@@ -112,35 +170,35 @@ func testOpenConnNonBlock(t *testing.T) {
 	p := newConnPool(Config{
 		Logger: testLogger{t: t},
 	})
-	srv1 := NewMockconnectionProvider(ctrl)
-	srv2 := NewMockconnectionProvider(ctrl)
-	srv3 := NewMockconnectionProvider(ctrl)
+	srv1 := NewMockconnectionProvider[net.Conn](ctrl)
+	srv2 := NewMockconnectionProvider[net.Conn](ctrl)
+	srv3 := NewMockconnectionProvider[net.Conn](ctrl)
 	p.connProviderFactory = newTestConnProviderFactory(srv1, srv2, srv3)
 
 	_, err := p.OpenConnNonBlock(context.Background())
 	ass.Equal(ErrNoServersRegistered, err)
 
-	p.RegisterServer("y") // srv1
-	p.RegisterServer("k") // srv2
-	p.RegisterServer("m") // srv3
+	ass.NoError(p.RegisterServer("y")) // srv1
+	ass.NoError(p.RegisterServer("k")) // srv2
+	ass.NoError(p.RegisterServer("m")) // srv3
 
 	srv1.EXPECT().retryTimeout().AnyTimes()
 	srv2.EXPECT().retryTimeout().AnyTimes()
 	srv3.EXPECT().retryTimeout().AnyTimes()
 
-	cn := &serverConn{}
+	cn := &resource[net.Conn]{}
 	srv1.EXPECT().getConnection(gomock.Any()).Return(cn, nil)
 	gotCn, err := p.OpenConnNonBlock(context.Background())
 	ass.NoError(err)
-	ass.Equal(cn, gotCn)
+	ass.Equal(cn, gotCn.(*serverConn).resource)
 
-	cn = &serverConn{}
+	cn = &resource[net.Conn]{}
 	srv2.EXPECT().getConnection(gomock.Any()).Return(cn, nil)
 	gotCn, err = p.OpenConnNonBlock(context.Background())
 	ass.NoError(err)
-	ass.Equal(cn, gotCn)
+	ass.Equal(cn, gotCn.(*serverConn).resource)
 
-	cn = &serverConn{}
+	cn = &resource[net.Conn]{}
 	gomock.InOrder(
 		srv3.EXPECT().getConnection(gomock.Any()).Return(nil, errRatelimit),
 		srv1.EXPECT().getConnection(gomock.Any()).Return(cn, nil),
@@ -148,7 +206,7 @@ func testOpenConnNonBlock(t *testing.T) {
 
 	gotCn, err = p.OpenConnNonBlock(context.Background())
 	ass.NoError(err)
-	ass.Equal(cn, gotCn)
+	ass.Equal(cn, gotCn.(*serverConn).resource)
 
 	gomock.InOrder(
 		srv2.EXPECT().getConnection(gomock.Any()).Return(nil, errRatelimit),
@@ -195,26 +253,26 @@ func testOpenConnBlock(t *testing.T) {
 		Logger: testLogger{t: t},
 		Clock:  cl,
 	})
-	srv1 := NewMockconnectionProvider(ctrl)
-	srv2 := NewMockconnectionProvider(ctrl)
+	srv1 := NewMockconnectionProvider[net.Conn](ctrl)
+	srv2 := NewMockconnectionProvider[net.Conn](ctrl)
 	p.connProviderFactory = newTestConnProviderFactory(srv1, srv2)
 
 	// check call will not be blocked if no servers were passed
 	_, err := p.OpenConn(context.Background())
 	ass.Equal(ErrNoServersRegistered, err)
 
-	p.RegisterServer("y")
-	p.RegisterServer("yt")
+	ass.NoError(p.RegisterServer("y"))
+	ass.NoError(p.RegisterServer("yt"))
 
 	// check success connection opening
-	cn := &serverConn{}
+	cn := &resource[net.Conn]{}
 	srv1.EXPECT().getConnection(gomock.Any()).Return(cn, nil)
 	gotCn, err := p.OpenConn(context.Background())
 	ass.NoError(err)
-	ass.Equal(cn, gotCn)
+	ass.Equal(cn, gotCn.(*serverConn).resource)
 
 	// check retry timeout
-	cn = &serverConn{}
+	cn = &resource[net.Conn]{}
 	gomock.InOrder(
 		srv2.EXPECT().getConnection(gomock.Any()).Return(nil, errServerIsDown),
 		srv1.EXPECT().getConnection(gomock.Any()).Return(nil, errServerIsDown),
@@ -242,7 +300,7 @@ func testOpenConnBlock(t *testing.T) {
 	<-ready
 
 	ass.NoError(err)
-	ass.Equal(cn, gotCn)
+	ass.Equal(cn, gotCn.(*serverConn).resource)
 
 	// check loop could be broken with context
 	ctx, cancel := context.WithCancel(context.Background())
@@ -287,12 +345,12 @@ func testOpenConnWithTimeout(t *testing.T) {
 		Clock:  cl,
 	})
 
-	srv := NewMockconnectionProvider(ctrl)
+	srv := NewMockconnectionProvider[net.Conn](ctrl)
 	srv.EXPECT().getConnection(gomock.Any()).Return(nil, errServerIsDown)
 	srv.EXPECT().retryTimeout().Return(time.Minute)
 
 	p.connProviderFactory = newTestConnProviderFactory(srv)
-	p.RegisterServer("yt")
+	ass.NoError(p.RegisterServer("yt"))
 
 	_, err := p.OpenConnWithTimeout(context.Background(), 300*time.Millisecond)
 
@@ -300,12 +358,422 @@ func testOpenConnWithTimeout(t *testing.T) {
 	ass.Equal("operation cancelled", err.Error())
 }
 
+func testStats(t *testing.T) {
+	t.Parallel()
+
+	ass := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	p := newConnPool(Config{})
+	ass.Equal(Stats{Servers: []ServerStats{}}, p.Stats())
+
+	srv1 := NewMockconnectionProvider[net.Conn](ctrl)
+	srv2 := NewMockconnectionProvider[net.Conn](ctrl)
+	p.connProviderFactory = newTestConnProviderFactory(srv1, srv2)
+
+	ass.NoError(p.RegisterServer("y"))
+	ass.NoError(p.RegisterServer("k"))
+
+	srv1.EXPECT().stats().Return(ServerStats{Address: "y", Up: true})
+	srv2.EXPECT().stats().Return(ServerStats{Address: "k", DialErrors: 1})
+
+	ass.Equal(Stats{
+		Servers: []ServerStats{
+			{Address: "y", Up: true},
+			{Address: "k", DialErrors: 1},
+		},
+	}, p.Stats())
+}
+
+func testAcquireHook(t *testing.T) {
+	t.Parallel()
+
+	ass := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var acquired []string
+	p := newConnPool(Config{
+		Hooks: &Hooks{
+			OnAcquire: func(addr string, wait time.Duration) {
+				acquired = append(acquired, addr)
+			},
+		},
+	})
+
+	srv := NewMockconnectionProvider[net.Conn](ctrl)
+	p.connProviderFactory = newTestConnProviderFactory(srv)
+	ass.NoError(p.RegisterServer("y"))
+
+	srv.EXPECT().retryTimeout().AnyTimes()
+	gomock.InOrder(
+		srv.EXPECT().getConnection(gomock.Any()).Return(&resource[net.Conn]{s: &server[net.Conn]{addr: "y"}}, nil),
+		srv.EXPECT().getConnection(gomock.Any()).Return(nil, errRatelimit),
+		srv.EXPECT().getConnection(gomock.Any()).Return(&resource[net.Conn]{s: &server[net.Conn]{addr: "y"}}, nil),
+	)
+
+	_, err := p.OpenConnNonBlock(context.Background())
+	ass.NoError(err)
+
+	_, err = p.OpenConnNonBlock(context.Background())
+	ass.Error(err)
+
+	_, err = p.OpenConn(context.Background())
+	ass.NoError(err)
+
+	ass.Equal([]string{"y", "y"}, acquired)
+}
+
+func testSetServerMode(t *testing.T) {
+	t.Parallel()
+
+	ass := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	p := newConnPool(Config{})
+
+	srv1 := NewMockconnectionProvider[net.Conn](ctrl)
+	srv2 := NewMockconnectionProvider[net.Conn](ctrl)
+	p.connProviderFactory = newTestConnProviderFactory(srv1, srv2)
+
+	ass.NoError(p.RegisterServer("y"))
+	ass.NoError(p.RegisterServer("k"))
+
+	srv2.EXPECT().setMode(ServerModeDrain)
+	ass.NoError(p.SetServerMode("k", ServerModeDrain))
+
+	err := p.SetServerMode("m", ServerModeDown)
+	ass.Equal(ErrUnknownServer, errors.Cause(err))
+
+	ass.Error(p.SetServerMode("y", ServerMode("xxx")))
+}
+
+func testServerOptions(t *testing.T) {
+	t.Parallel()
+
+	ass := require.New(t)
+
+	p := newConnPool(Config{
+		MaxRPS:            100,
+		MaxConnsPerServer: 2,
+	})
+
+	ass.NoError(p.RegisterServer("primary", ServerMaxRPS(1000), ServerMaxConns(10), ServerMaxStreams(4)))
+	ass.NoError(p.RegisterServer("replica",
+		ServerConnectTimeout(time.Minute), ServerBackoff(time.Second, 0), ServerMaxRPS(0)))
+
+	st := p.Stats()
+	ass.Equal(ServerLimits{
+		MaxConns:               10,
+		MaxStreamsPerConn:      4,
+		MaxRPS:                 1000,
+		ConnectTimeout:         DefaultConnectTimeout,
+		InitialBackoffInterval: DefaultInitBackoffInterval,
+		MaxBackoffInterval:     DefaultMaxBackoffInterval,
+	}, st.Servers[0].Limits)
+
+	ass.Equal(ServerLimits{
+		MaxConns:               2,
+		MaxStreamsPerConn:      DefaultMaxStreamsPerConn,
+		MaxRPS:                 100,
+		ConnectTimeout:         time.Minute,
+		InitialBackoffInterval: time.Second,
+		MaxBackoffInterval:     DefaultMaxBackoffInterval,
+	}, st.Servers[1].Limits)
+
+	// effective config of the server is invalid: default MaxBackoffInterval is less than the override
+	ass.EqualError(p.RegisterServer("remote", ServerBackoff(2*time.Minute, 0)), "can't register server remote: "+
+		"invalid config: MaxBackoffInterval (30s) should not be less than InitialBackoffInterval (2m0s)")
+	ass.EqualError(p.RegisterServer("remote", ServerMaxRPS(-5)), "can't register server remote: "+
+		"invalid config: MaxRPS should not be negative (got -5)")
+	ass.EqualError(p.RegisterServer("remote", ServerBackoff(0, -time.Second)), "can't register server remote: "+
+		"invalid config: MaxBackoffInterval should not be negative (got -1s)")
+	ass.Len(p.Stats().Servers, 2)
+}
+
+func testUpdateConfig(t *testing.T) {
+	t.Parallel()
+
+	ass := require.New(t)
+
+	var dials1, dials2 int
+	dialer := func(dials *int) Dialer {
+		return DialerFunc(func(ctx context.Context, address string) (net.Conn, error) {
+			*dials++
+			cn, _ := net.Pipe()
+			return cn, nil
+		})
+	}
+
+	p := newConnPool(Config{
+		MaxRPS:            math.MaxInt32,
+		MaxConnsPerServer: 3,
+		Dialer:            dialer(&dials1),
+	})
+
+	ass.NoError(p.RegisterServer("a"))
+	ass.NoError(p.RegisterServer("b", ServerMaxConns(5), ServerBackoff(10*time.Second, 0)))
+
+	var conns []Conn
+	for i := 0; i < 3; i++ {
+		cn, err := p.OpenConnToServer(context.Background(), "a")
+		ass.NoError(err)
+		conns = append(conns, cn)
+	}
+
+	ass.NoError(conns[0].ReturnToPool())
+	ass.NoError(conns[1].ReturnToPool())
+	ass.Equal(2, p.Stats().Servers[0].IdleConns)
+
+	// invalid configs aren't applied
+	ass.EqualError(p.UpdateConfig(Config{MaxRPS: -1, Dialer: dialer(&dials2)}),
+		"invalid config: MaxRPS should not be negative (got -1)")
+	ass.EqualError(p.UpdateConfig(Config{MaxConnsPerServer: 1, InitialBackoffInterval: time.Second,
+		MaxBackoffInterval: 5 * time.Second, Dialer: dialer(&dials2)}),
+		"server b: invalid config: MaxBackoffInterval (5s) should not be less than InitialBackoffInterval (10s)")
+	ass.Equal(3, p.Stats().Servers[0].Limits.MaxConns)
+
+	ass.NoError(p.UpdateConfig(Config{
+		MaxRPS:            math.MaxInt32,
+		MaxConnsPerServer: 1,
+		Dialer:            dialer(&dials2),
+	}))
+
+	// idle connections are closed, borrowed one is kept
+	st := p.Stats()
+	ass.Equal(1, st.Servers[0].OpenConns)
+	ass.Equal(1, st.Servers[0].InUseConns)
+	ass.Equal(1, st.Servers[0].Limits.MaxConns)
+	ass.Equal(5, st.Servers[1].Limits.MaxConns) // override is kept
+
+	_, err := p.OpenConnNonBlock(context.Background()) // "a" is full: "b" is used
+	ass.NoError(err)
+
+	ass.NoError(conns[2].ReturnToPool())
+	ass.Equal(1, p.Stats().Servers[0].IdleConns)
+
+	ass.Equal(3, dials1)
+	ass.Equal(1, dials2) // new dialer is used
+}
+
+func testUpdateConfigConcurrent(t *testing.T) {
+	t.Parallel()
+
+	ass := require.New(t)
+
+	p := newConnPool(Config{
+		MaxRPS: math.MaxInt32,
+		Dialer: DialerFunc(func(ctx context.Context, address string) (net.Conn, error) {
+			cn, _ := net.Pipe()
+			return cn, nil
+		}),
+	})
+
+	ass.NoError(p.RegisterServer("a"))
+	ass.NoError(p.RegisterServer("b"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				_ = p.Do(context.Background(), func(Conn) error { return nil })
+				_ = p.DoWithRetry(context.Background(), RetryPolicy{MaxAttempts: 1}, func(Conn) error { return nil })
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		ass.NoError(p.UpdateConfig(Config{
+			MaxRPS:                 math.MaxInt32,
+			MaxConnsPerServer:      1 + i%3,
+			InitialBackoffInterval: time.Duration(1+i%2) * time.Millisecond,
+		}))
+	}
+
+	wg.Wait()
+
+	ass.Equal(2, p.serversConfig().MaxConnsPerServer)
+	ass.Equal(DefaultMaxConnsPerServer, p.cfg.MaxConnsPerServer) // pool config isn't changed
+
+	// dialer isn't passed: the current one (with its state) is kept
+	p = newConnPool(Config{})
+	dialer := p.serversConfig().Dialer
+
+	ass.NoError(p.UpdateConfig(Config{MaxRPS: 10}))
+	ass.True(dialer == p.serversConfig().Dialer)
+}
+
+func testOpenConnToServer(t *testing.T) {
+	t.Parallel()
+
+	ass := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	p := newConnPool(Config{})
+
+	srv1 := NewMockconnectionProvider[net.Conn](ctrl)
+	srv2 := NewMockconnectionProvider[net.Conn](ctrl)
+	p.connProviderFactory = newTestConnProviderFactory(srv1, srv2)
+
+	ass.NoError(p.RegisterServer("y"))
+	ass.NoError(p.RegisterServer("k"))
+
+	cn := &resource[net.Conn]{}
+	srv2.EXPECT().getConnection(gomock.Any()).Return(cn, nil)
+	gotCn, err := p.OpenConnToServer(context.Background(), "k")
+	ass.NoError(err)
+	ass.Equal(cn, gotCn.(*serverConn).resource)
+
+	srv2.EXPECT().getConnection(gomock.Any()).Return(nil, errServerIsDown)
+	_, err = p.OpenConnToServer(context.Background(), "k")
+	ass.Equal(errServerIsDown, err)
+
+	_, err = p.OpenConnToServer(context.Background(), "m")
+	ass.Equal(ErrUnknownServer, errors.Cause(err))
+}
+
+func testOpenConnToServerBlock(t *testing.T) {
+	t.Parallel()
+
+	ass := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	p := newConnPool(Config{})
+
+	srv := NewMockconnectionProvider[net.Conn](ctrl)
+	p.connProviderFactory = newTestConnProviderFactory(srv)
+
+	ass.NoError(p.RegisterServer("k"))
+
+	cn := &resource[net.Conn]{}
+	gomock.InOrder(
+		srv.EXPECT().getConnection(gomock.Any()).Return(nil, errors.Wrap(errRatelimit, "test")),
+		srv.EXPECT().retryTimeout().Return(time.Millisecond),
+		srv.EXPECT().getConnection(gomock.Any()).Return(cn, nil),
+	)
+	gotCn, err := p.OpenConnToServerBlock(context.Background(), "k")
+	ass.NoError(err)
+	ass.Equal(cn, gotCn.(*serverConn).resource)
+
+	// server which is down isn't waited for
+	srv.EXPECT().getConnection(gomock.Any()).Return(nil, errServerIsDown)
+	_, err = p.OpenConnToServerBlock(context.Background(), "k")
+	ass.Equal(errServerIsDown, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	srv.EXPECT().getConnection(gomock.Any()).Return(nil, errRatelimit)
+	srv.EXPECT().retryTimeout().Return(time.Hour)
+	_, err = p.OpenConnToServerBlock(ctx, "k")
+	ass.EqualError(err, "operation cancelled")
+
+	_, err = p.OpenConnToServerBlock(context.Background(), "m")
+	ass.Equal(ErrUnknownServer, errors.Cause(err))
+}
+
+type testClient struct {
+	addr    string
+	invalid bool
+	closed  bool
+}
+
+type testClientFactory struct {
+	created int
+}
+
+func (f *testClientFactory) Create(ctx context.Context, addr string) (*testClient, error) {
+	f.created++
+	return &testClient{addr: addr}, nil
+}
+
+func (f *testClientFactory) Close(c *testClient) error {
+	c.closed = true
+	return nil
+}
+
+func (f *testClientFactory) Validate(c *testClient) error {
+	if c.invalid {
+		return fmt.Errorf("invalid client")
+	}
+
+	return nil
+}
+
+func testGenericPool(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	f := &testClientFactory{}
+	p := NewPool[*testClient](Config{
+		MaxRPS: math.MaxInt32,
+		Logger: testLogger{t: t},
+	}, f)
+
+	_, err := p.GetNonBlock(context.Background())
+	ass.Equal(ErrNoServersRegistered, err)
+
+	ass.NoError(p.RegisterServer("y"))
+
+	r, err := p.Get(context.Background())
+	ass.NoError(err)
+	ass.Equal("y", r.ServerAddr())
+	ass.Equal("y", r.Value().addr)
+
+	c := r.Value()
+	ass.NoError(r.ReturnToPool())
+
+	// idle client is reused
+	r, err = p.GetNonBlock(context.Background())
+	ass.NoError(err)
+	ass.Equal(c, r.Value())
+	ass.Equal(1, f.created)
+
+	// invalid client is closed
+	c.invalid = true
+	ass.NoError(r.ReturnToPool())
+
+	ass.NoError(p.Do(context.Background(), func(got *testClient) error {
+		ass.NotEqual(c, got)
+		return nil
+	}))
+
+	ass.True(c.closed)
+	ass.Equal(2, f.created)
+
+	// broken client is closed
+	err = p.Do(context.Background(), func(got *testClient) error {
+		c = got
+		return fmt.Errorf("broken")
+	})
+
+	ass.Error(err)
+	ass.True(c.closed)
+	ass.Equal(0, p.Stats().Servers[0].OpenConns)
+}
+
 func testOpenConn(t *testing.T) {
 	t.Parallel()
 
 	t.Run("open_conn_non_block", testOpenConnNonBlock)
 	t.Run("open_conn_block", testOpenConnBlock)
 	t.Run("open_conn_with_timeout", testOpenConnWithTimeout)
+	t.Run("open_conn_to_server", testOpenConnToServer)
+	t.Run("update_config_concurrent", testUpdateConfigConcurrent)
+	t.Run("open_conn_to_server_block", testOpenConnToServerBlock)
+	t.Run("acquire_hook", testAcquireHook)
 }
 
 func testOpenConnection(t *testing.T) {
@@ -313,6 +781,11 @@ func testOpenConnection(t *testing.T) {
 
 	t.Run("create_default_conn_pool", testDefaultConnPoolCreation)
 	t.Run("open_conn", testOpenConn)
+	t.Run("stats", testStats)
+	t.Run("set_server_mode", testSetServerMode)
+	t.Run("generic_pool", testGenericPool)
+	t.Run("server_options", testServerOptions)
+	t.Run("update_config", testUpdateConfig)
 }
 
 func testConfigDefaults(t *testing.T) {
@@ -320,6 +793,7 @@ func testConfigDefaults(t *testing.T) {
 
 	t.Run("config_parsing", testConfigParsing)
 	t.Run("fill_defaults", testConfigFillDefaults)
+	t.Run("validation", testConfigValidation)
 }
 
 func TestConnPool(t *testing.T) {
@@ -0,0 +1,191 @@
+// Package prometheus implements prometheus.Collector over goconnpool.ConnPool.
+//
+// Collector exports connections and servers state of one pool labelled by server address.
+// Use different pool names to export metrics of multiple pools into one registry.
+package prometheus
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/derElektrobesen/goconnpool"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "goconnpool"
+
+// Collector implements prometheus.Collector interface.
+//
+// Gauges and counters are taken from goconnpool.ConnPool.Stats() on each scrape.
+// Histograms are filled by the hooks and the dialer returned by Hooks and WrapDialer calls.
+type Collector struct {
+	pool goconnpool.ConnPool
+
+	openConns     *prom.Desc
+	idleConns     *prom.Desc
+	inUseConns    *prom.Desc
+	serverUp      *prom.Desc
+	dials         *prom.Desc
+	dialErrors    *prom.Desc
+	ratelimitHits *prom.Desc
+	bytesRead     *prom.Desc
+	bytesWritten  *prom.Desc
+
+	acquireWait *prom.HistogramVec
+	dialLatency *prom.HistogramVec
+}
+
+// NewCollector creates new collector.
+// name is exported as "pool" label of each metric.
+func NewCollector(name string) *Collector {
+	labels := prom.Labels{"pool": name}
+	desc := func(name, help string) *prom.Desc {
+		return prom.NewDesc(prom.BuildFQName(namespace, "", name), help, []string{"server"}, labels)
+	}
+
+	return &Collector{
+		openConns:     desc("open_connections", "Number of opened connections (both idle and in use)."),
+		idleConns:     desc("idle_connections", "Number of connections returned into pool."),
+		inUseConns:    desc("in_use_connections", "Number of connections owned by the user."),
+		serverUp:      desc("server_up", "Whether the server is up (1) or is marked down or waits for backoff interval (0)."),
+		dials:         desc("dials_total", "Total number of dials."),
+		dialErrors:    desc("dial_errors_total", "Total number of failed dials."),
+		ratelimitHits: desc("ratelimit_hits_total", "Total number of ratelimited connection requests."),
+		bytesRead:     desc("read_bytes_total", "Total number of bytes read (see goconnpool.CountBytes)."),
+		bytesWritten:  desc("written_bytes_total", "Total number of bytes written (see goconnpool.CountBytes)."),
+
+		acquireWait: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace:   namespace,
+			Name:        "acquire_wait_seconds",
+			Help:        "Time spent to get connection from the pool.",
+			ConstLabels: labels,
+		}, []string{"server"}),
+
+		dialLatency: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace:   namespace,
+			Name:        "dial_duration_seconds",
+			Help:        "Time spent to dial the server.",
+			ConstLabels: labels,
+		}, []string{"server"}),
+	}
+}
+
+// WrapPool registers the pool in the collector: pool state will be exported by the collector after this call.
+// The pool passed is returned as is: connections acquire time is measured by the hooks (see Hooks).
+//
+// This function is a part of initialization: call it before the collector registration.
+func (c *Collector) WrapPool(p goconnpool.ConnPool) goconnpool.ConnPool {
+	c.pool = p
+	return p
+}
+
+// Hooks returns the hooks which measure connections acquire time of all pool calls (OpenConn* and Do*).
+// Use returned hooks as goconnpool.Config.Hooks. Callbacks of h (if not nil) are called too.
+//
+//	cfg.Hooks = collector.Hooks(cfg.Hooks)
+func (c *Collector) Hooks(h *goconnpool.Hooks) *goconnpool.Hooks {
+	res := &goconnpool.Hooks{}
+	if h != nil {
+		*res = *h
+	}
+
+	next := res.OnAcquire
+	res.OnAcquire = func(addr string, wait time.Duration) {
+		c.acquireWait.WithLabelValues(addr).Observe(wait.Seconds())
+
+		if next != nil {
+			next(addr, wait)
+		}
+	}
+
+	return res
+}
+
+// WrapDialer returns the dialer which measures dials latency.
+// Use returned dialer as goconnpool.Config.Dialer.
+//
+// The pool default dialer is used if d is nil (see goconnpool.DefaultDialer): pass goconnpool.DefaultDialer(cfg)
+// explicitly to use the pool clock. Address validation (see goconnpool.AddressValidator) is delegated to d.
+func (c *Collector) WrapDialer(d goconnpool.Dialer) goconnpool.Dialer {
+	if d == nil {
+		d = goconnpool.DefaultDialer(goconnpool.Config{})
+	}
+
+	wrapped := &dialer{
+		Dialer: d,
+		c:      c,
+	}
+
+	if v, ok := d.(goconnpool.AddressValidator); ok {
+		return validatingDialer{Dialer: wrapped, v: v}
+	}
+
+	return wrapped
+}
+
+type validatingDialer struct {
+	goconnpool.Dialer
+	v goconnpool.AddressValidator
+}
+
+func (d validatingDialer) ValidateAddress(address string) error {
+	return d.v.ValidateAddress(address)
+}
+
+// Describe implements prometheus.Collector interface.
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	ch <- c.openConns
+	ch <- c.idleConns
+	ch <- c.inUseConns
+	ch <- c.serverUp
+	ch <- c.dials
+	ch <- c.dialErrors
+	ch <- c.ratelimitHits
+	ch <- c.bytesRead
+	ch <- c.bytesWritten
+
+	c.acquireWait.Describe(ch)
+	c.dialLatency.Describe(ch)
+}
+
+// Collect implements prometheus.Collector interface.
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	if c.pool != nil {
+		for _, s := range c.pool.Stats().Servers {
+			c.collectServer(ch, s)
+		}
+	}
+
+	c.acquireWait.Collect(ch)
+	c.dialLatency.Collect(ch)
+}
+
+func (c *Collector) collectServer(ch chan<- prom.Metric, s goconnpool.ServerStats) {
+	var up float64
+	if s.Up {
+		up = 1
+	}
+
+	ch <- prom.MustNewConstMetric(c.openConns, prom.GaugeValue, float64(s.OpenConns), s.Address)
+	ch <- prom.MustNewConstMetric(c.idleConns, prom.GaugeValue, float64(s.IdleConns), s.Address)
+	ch <- prom.MustNewConstMetric(c.inUseConns, prom.GaugeValue, float64(s.InUseConns), s.Address)
+	ch <- prom.MustNewConstMetric(c.serverUp, prom.GaugeValue, up, s.Address)
+	ch <- prom.MustNewConstMetric(c.dials, prom.CounterValue, float64(s.Dials), s.Address)
+	ch <- prom.MustNewConstMetric(c.dialErrors, prom.CounterValue, float64(s.DialErrors), s.Address)
+	ch <- prom.MustNewConstMetric(c.ratelimitHits, prom.CounterValue, float64(s.RatelimitHits), s.Address)
+	ch <- prom.MustNewConstMetric(c.bytesRead, prom.CounterValue, float64(s.BytesRead), s.Address)
+	ch <- prom.MustNewConstMetric(c.bytesWritten, prom.CounterValue, float64(s.BytesWritten), s.Address)
+}
+
+type dialer struct {
+	goconnpool.Dialer
+	c *Collector
+}
+
+func (d *dialer) Dial(ctx context.Context, address string) (net.Conn, error) {
+	start := time.Now()
+	cn, err := d.Dialer.Dial(ctx, address)
+	d.c.dialLatency.WithLabelValues(address).Observe(time.Since(start).Seconds())
+	return cn, err
+}
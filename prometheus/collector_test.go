@@ -0,0 +1,110 @@
+package prometheus
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/derElektrobesen/goconnpool"
+	"github.com/derElektrobesen/goconnpool/internal/pooltest"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	c := NewCollector("test")
+
+	var acquired []string
+	p := c.WrapPool(goconnpool.NewConnPool(goconnpool.Config{
+		MaxRPS: math.MaxInt32,
+		Dialer: c.WrapDialer(pooltest.Dialer{}),
+		Hooks: c.Hooks(&goconnpool.Hooks{
+			OnAcquire: func(addr string, wait time.Duration) {
+				acquired = append(acquired, addr)
+			},
+		}),
+	}))
+
+	pooltest.RegisterServers(t, p)
+
+	cn, err := p.OpenConnNonBlock(context.Background()) // "ok" is dialed
+	ass.NoError(err)
+	ass.NoError(cn.ReturnToPool())
+
+	cn, err = p.OpenConnNonBlock(context.Background()) // "bad" is down, "ok" is reused
+	ass.NoError(err)
+	ass.Equal("ok", cn.ServerAddr())
+
+	_, err = p.OpenConnNonBlock(context.Background()) // "bad" waits for backoff, "ok" has no free conns
+	ass.Error(err)
+
+	ass.NoError(testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP goconnpool_open_connections Number of opened connections (both idle and in use).
+# TYPE goconnpool_open_connections gauge
+goconnpool_open_connections{pool="test",server="bad"} 0
+goconnpool_open_connections{pool="test",server="ok"} 1
+# HELP goconnpool_idle_connections Number of connections returned into pool.
+# TYPE goconnpool_idle_connections gauge
+goconnpool_idle_connections{pool="test",server="bad"} 0
+goconnpool_idle_connections{pool="test",server="ok"} 0
+# HELP goconnpool_in_use_connections Number of connections owned by the user.
+# TYPE goconnpool_in_use_connections gauge
+goconnpool_in_use_connections{pool="test",server="bad"} 0
+goconnpool_in_use_connections{pool="test",server="ok"} 1
+# HELP goconnpool_server_up Whether the server is up (1) or is marked down or waits for backoff interval (0).
+# TYPE goconnpool_server_up gauge
+goconnpool_server_up{pool="test",server="bad"} 0
+goconnpool_server_up{pool="test",server="ok"} 1
+# HELP goconnpool_dials_total Total number of dials.
+# TYPE goconnpool_dials_total counter
+goconnpool_dials_total{pool="test",server="bad"} 1
+goconnpool_dials_total{pool="test",server="ok"} 1
+# HELP goconnpool_dial_errors_total Total number of failed dials.
+# TYPE goconnpool_dial_errors_total counter
+goconnpool_dial_errors_total{pool="test",server="bad"} 1
+goconnpool_dial_errors_total{pool="test",server="ok"} 0
+# HELP goconnpool_ratelimit_hits_total Total number of ratelimited connection requests.
+# TYPE goconnpool_ratelimit_hits_total counter
+goconnpool_ratelimit_hits_total{pool="test",server="bad"} 1
+goconnpool_ratelimit_hits_total{pool="test",server="ok"} 1
+`),
+		"goconnpool_open_connections",
+		"goconnpool_idle_connections",
+		"goconnpool_in_use_connections",
+		"goconnpool_server_up",
+		"goconnpool_dials_total",
+		"goconnpool_dial_errors_total",
+		"goconnpool_ratelimit_hits_total",
+	))
+
+	ass.Equal(1, testutil.CollectAndCount(c, "goconnpool_acquire_wait_seconds"))
+	ass.Equal([]string{"ok", "ok"}, acquired) // hooks passed are called too
+
+	// connections borrowed by Do* calls are measured too
+	ass.NoError(cn.Close())
+	ass.NoError(p.Do(context.Background(), func(goconnpool.Conn) error { return nil }))
+	ass.Equal([]string{"ok", "ok", "ok"}, acquired)
+	ass.Equal(2, testutil.CollectAndCount(c, "goconnpool_dial_duration_seconds"))
+}
+
+func TestWrapDialerValidation(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	c := NewCollector("test")
+	p := goconnpool.NewConnPool(goconnpool.Config{
+		Dialer: c.WrapDialer(nil),
+	})
+
+	// addresses are validated by the wrapped dialer
+	ass.NoError(p.RegisterServer("127.0.0.1:80"))
+	ass.Error(p.RegisterServer("udp://127.0.0.1:80"))
+
+	_, ok := c.WrapDialer(pooltest.Dialer{}).(goconnpool.AddressValidator)
+	ass.False(ok)
+}
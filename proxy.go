@@ -0,0 +1,278 @@
+package goconnpool
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ProxyAuth holds the credentials used to authenticate on the proxy.
+type ProxyAuth struct {
+	Username string
+	Password string
+}
+
+// ProxyDialer is the implementation of Dialer interface which tunnels connections to the registered servers
+// through the proxy (like a bastion host). Use NewSOCKS5Dialer or NewHTTPConnectDialer to create it.
+//
+// Proxy is dialed using TCPDialer, so proxy address could be URL-style one too (see TCPDialer).
+// Registered addresses are passed to the proxy as is: unix sockets aren't supported.
+// Proxy handshake is performed within Config.ConnectTimeout.
+//
+// Use TLSDialer.WithDialer to establish TLS connections through the proxy.
+type ProxyDialer struct {
+	tcp TCPDialer
+
+	proxy     string
+	auth      *ProxyAuth
+	handshake func(cn net.Conn, address string, auth *ProxyAuth) (net.Conn, error)
+}
+
+// NewSOCKS5Dialer creates ProxyDialer using SOCKS5 proxy with address passed.
+// Username/password authentication is used if auth isn't nil.
+func NewSOCKS5Dialer(proxy string, auth *ProxyAuth) *ProxyDialer {
+	return &ProxyDialer{
+		proxy:     proxy,
+		auth:      auth,
+		handshake: socks5Handshake,
+	}
+}
+
+// NewHTTPConnectDialer creates ProxyDialer using HTTP proxy with address passed (CONNECT method is used).
+// Basic authentication is used if auth isn't nil.
+func NewHTTPConnectDialer(proxy string, auth *ProxyAuth) *ProxyDialer {
+	return &ProxyDialer{
+		proxy:     proxy,
+		auth:      auth,
+		handshake: httpConnectHandshake,
+	}
+}
+
+// Dial dials the proxy and establishes the tunnel to the address passed.
+func (d *ProxyDialer) Dial(ctx context.Context, address string) (net.Conn, error) {
+	network, address, err := parseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(network, "unix") {
+		return nil, errors.Errorf("%s can't be dialed through the proxy", address)
+	}
+
+	cn, err := d.tcp.Dial(ctx, d.proxy)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't dial proxy %s", d.proxy)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		cn.SetDeadline(deadline) // nolint:errcheck
+	}
+
+	tunnel, err := d.handshake(cn, address, d.auth)
+	if err != nil {
+		cn.Close() // nolint:errcheck
+		return nil, errors.Wrapf(err, "can't establish tunnel to %s through proxy %s", address, d.proxy)
+	}
+
+	cn.SetDeadline(time.Time{}) // nolint:errcheck
+	return tunnel, nil
+}
+
+// ValidateAddress implements AddressValidator interface.
+func (d *ProxyDialer) ValidateAddress(address string) error {
+	network, _, err := parseAddress(address)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(network, "unix") {
+		return errors.Errorf("%s can't be dialed through the proxy", address)
+	}
+
+	return nil
+}
+
+const (
+	socks5Version = 5
+
+	socks5AuthNone     = 0
+	socks5AuthPassword = 2
+	socks5AuthNoMethod = 0xff
+
+	socks5CmdConnect = 1
+
+	socks5AddrIPv4   = 1
+	socks5AddrDomain = 3
+	socks5AddrIPv6   = 4
+)
+
+// socks5Handshake implements client side of RFC 1928 (CONNECT command) and RFC 1929 (authentication).
+func socks5Handshake(cn net.Conn, address string, auth *ProxyAuth) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid port %q", portStr)
+	}
+
+	method := byte(socks5AuthNone)
+	if auth != nil {
+		method = socks5AuthPassword
+	}
+
+	if _, err := cn.Write([]byte{socks5Version, 1, method}); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(cn, buf); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if buf[0] != socks5Version {
+		return nil, errors.Errorf("unexpected SOCKS version %d", buf[0])
+	}
+
+	if buf[1] == socks5AuthNoMethod {
+		return nil, errors.New("no acceptable authentication methods")
+	}
+
+	if buf[1] != method {
+		return nil, errors.Errorf("authentication method isn't accepted by proxy (method %#x)", buf[1])
+	}
+
+	if auth != nil {
+		if len(auth.Username) > 255 || len(auth.Password) > 255 {
+			return nil, errors.New("too long username or password")
+		}
+
+		req := []byte{1, byte(len(auth.Username))}
+		req = append(req, auth.Username...)
+		req = append(req, byte(len(auth.Password)))
+		req = append(req, auth.Password...)
+
+		if _, err := cn.Write(req); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		if _, err := io.ReadFull(cn, buf); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		if buf[1] != 0 {
+			return nil, errors.New("proxy authentication failed")
+		}
+	}
+
+	req := []byte{socks5Version, socks5CmdConnect, 0}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return nil, errors.Errorf("too long host %q", host)
+		}
+
+		req = append(req, socks5AddrDomain, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, socks5AddrIPv4)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, socks5AddrIPv6)
+		req = append(req, ip.To16()...)
+	}
+
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := cn.Write(req); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// VER, REP, RSV, ATYP
+	resp := make([]byte, 4)
+	if _, err := io.ReadFull(cn, resp); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if resp[1] != 0 {
+		return nil, errors.Errorf("proxy rejected the request (reply code %d)", resp[1])
+	}
+
+	// bound address and port are skipped
+	var skip int
+	switch resp[3] {
+	case socks5AddrIPv4:
+		skip = net.IPv4len + 2
+	case socks5AddrIPv6:
+		skip = net.IPv6len + 2
+	case socks5AddrDomain:
+		if _, err := io.ReadFull(cn, buf[:1]); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		skip = int(buf[0]) + 2
+	default:
+		return nil, errors.Errorf("unexpected address type %d", resp[3])
+	}
+
+	if _, err := io.CopyN(io.Discard, cn, int64(skip)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return cn, nil
+}
+
+func httpConnectHandshake(cn net.Conn, address string, auth *ProxyAuth) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+
+	if auth != nil {
+		req.Header.Set("Proxy-Authorization",
+			"Basic "+base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password)))
+	}
+
+	if err := req.Write(cn); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	br := bufio.NewReader(cn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close() // nolint:errcheck
+		return nil, errors.Errorf("proxy responded with %q", resp.Status)
+	}
+
+	if br.Buffered() > 0 {
+		// server has spoken first: bytes read ahead shouldn't be lost
+		return &bufferedConn{Conn: cn, r: br}, nil
+	}
+
+	return cn, nil
+}
+
+type bufferedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (cn *bufferedConn) Read(b []byte) (int, error) {
+	return cn.r.Read(b)
+}
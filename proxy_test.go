@@ -0,0 +1,255 @@
+package goconnpool
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func listenTest(t *testing.T, serve func(cn net.Conn)) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			cn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go serve(cn)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func echoTestServer(t *testing.T) string {
+	return listenTest(t, func(cn net.Conn) {
+		defer cn.Close()
+		io.Copy(cn, cn) // nolint:errcheck
+	})
+}
+
+func pipeTestConns(a, b net.Conn) {
+	go func() {
+		io.Copy(a, b) // nolint:errcheck
+		a.Close()
+	}()
+
+	io.Copy(b, a) // nolint:errcheck
+	b.Close()
+}
+
+// socks5TestServer is the minimal SOCKS5 proxy stand-in: only CONNECT command is supported.
+func socks5TestServer(t *testing.T, auth *ProxyAuth) string {
+	return listenTest(t, func(cn net.Conn) {
+		defer cn.Close()
+
+		buf := make([]byte, 256)
+		if _, err := io.ReadFull(cn, buf[:2]); err != nil {
+			return
+		}
+
+		methods := make([]byte, buf[1])
+		if _, err := io.ReadFull(cn, methods); err != nil {
+			return
+		}
+
+		want := byte(socks5AuthNone)
+		if auth != nil {
+			want = socks5AuthPassword
+		}
+
+		found := false
+		for _, m := range methods {
+			found = found || m == want
+		}
+
+		if !found {
+			cn.Write([]byte{socks5Version, socks5AuthNoMethod}) // nolint:errcheck
+			return
+		}
+
+		cn.Write([]byte{socks5Version, want}) // nolint:errcheck
+
+		if auth != nil {
+			var user, pass []byte
+			if _, err := io.ReadFull(cn, buf[:2]); err != nil {
+				return
+			}
+
+			user = make([]byte, buf[1])
+			io.ReadFull(cn, user)    // nolint:errcheck
+			io.ReadFull(cn, buf[:1]) // nolint:errcheck
+			pass = make([]byte, buf[0])
+			io.ReadFull(cn, pass) // nolint:errcheck
+
+			if string(user) != auth.Username || string(pass) != auth.Password {
+				cn.Write([]byte{1, 1}) // nolint:errcheck
+				return
+			}
+
+			cn.Write([]byte{1, 0}) // nolint:errcheck
+		}
+
+		if _, err := io.ReadFull(cn, buf[:4]); err != nil || buf[1] != socks5CmdConnect {
+			return
+		}
+
+		var host string
+		switch buf[3] {
+		case socks5AddrIPv4:
+			io.ReadFull(cn, buf[:net.IPv4len]) // nolint:errcheck
+			host = net.IP(buf[:net.IPv4len]).String()
+		case socks5AddrDomain:
+			io.ReadFull(cn, buf[:1])              // nolint:errcheck
+			io.ReadFull(cn, buf[1:1+int(buf[0])]) // nolint:errcheck
+			host = string(buf[1 : 1+int(buf[0])])
+		default:
+			return
+		}
+
+		io.ReadFull(cn, buf[:2]) // nolint:errcheck
+		port := binary.BigEndian.Uint16(buf[:2])
+
+		target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+		if err != nil {
+			cn.Write([]byte{socks5Version, 5, 0, socks5AddrIPv4, 0, 0, 0, 0, 0, 0}) // nolint:errcheck
+			return
+		}
+
+		cn.Write([]byte{socks5Version, 0, 0, socks5AddrIPv4, 0, 0, 0, 0, 0, 0}) // nolint:errcheck
+		pipeTestConns(cn, target)
+	})
+}
+
+func httpConnectTestServer(t *testing.T, auth *ProxyAuth) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if auth != nil {
+			expected := "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password))
+			if r.Header.Get("Proxy-Authorization") != expected {
+				w.WriteHeader(http.StatusProxyAuthRequired)
+				return
+			}
+		}
+
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		cn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			target.Close()
+			return
+		}
+
+		rw.WriteString("HTTP/1.1 200 Connection established\r\n\r\n") // nolint:errcheck
+		rw.Flush()                                                    // nolint:errcheck
+		pipeTestConns(cn, target)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv.Listener.Addr().String()
+}
+
+func testProxyEcho(t *testing.T, d Dialer, target string) {
+	ass := require.New(t)
+
+	p := NewConnPool(Config{Dialer: d})
+	ass.Error(p.RegisterServer("unix:///tmp/app.sock"))
+	ass.NoError(p.RegisterServer(target))
+
+	cn, err := p.OpenConn(context.Background())
+	ass.NoError(err)
+	defer cn.Close()
+
+	_, err = cn.Write([]byte("ping"))
+	ass.NoError(err)
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(cn, buf)
+	ass.NoError(err)
+	ass.Equal("ping", string(buf))
+}
+
+func TestSOCKS5Dialer(t *testing.T) {
+	t.Parallel()
+
+	auth := &ProxyAuth{Username: "user", Password: "pass"}
+	proxy := socks5TestServer(t, auth)
+	target := echoTestServer(t)
+
+	testProxyEcho(t, NewSOCKS5Dialer(proxy, auth), target)
+
+	_, err := NewSOCKS5Dialer(proxy, &ProxyAuth{Username: "user"}).Dial(context.Background(), target)
+	require.Error(t, err)
+
+	_, err = NewSOCKS5Dialer(proxy, nil).Dial(context.Background(), target)
+	require.Error(t, err)
+
+	// no authentication
+	testProxyEcho(t, NewSOCKS5Dialer(socks5TestServer(t, nil), nil), target)
+}
+
+func TestHTTPConnectDialer(t *testing.T) {
+	t.Parallel()
+
+	auth := &ProxyAuth{Username: "user", Password: "pass"}
+	proxy := httpConnectTestServer(t, auth)
+	target := echoTestServer(t)
+
+	testProxyEcho(t, NewHTTPConnectDialer(proxy, auth), target)
+
+	_, err := NewHTTPConnectDialer(proxy, nil).Dial(context.Background(), target)
+	require.Error(t, err)
+}
+
+func TestTLSDialerThroughProxy(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok") // nolint:errcheck
+	}))
+	defer srv.Close()
+
+	addr := srv.Listener.Addr().String()
+	d := NewTLSDialer(srv.Client().Transport.(*http.Transport).TLSClientConfig, nil).
+		WithDialer(NewSOCKS5Dialer(socks5TestServer(t, nil), nil))
+
+	cn, err := d.Dial(context.Background(), addr)
+	ass.NoError(err)
+	defer cn.Close()
+
+	ass.True(cn.(*tls.Conn).ConnectionState().HandshakeComplete)
+
+	_, err = io.WriteString(cn, "GET / HTTP/1.0\r\nHost: example.com\r\n\r\n")
+	ass.NoError(err)
+
+	resp, err := http.ReadResponse(bufio.NewReader(cn), nil)
+	ass.NoError(err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	ass.NoError(err)
+	ass.Equal("ok", string(body))
+}
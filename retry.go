@@ -0,0 +1,170 @@
+package goconnpool
+
+import (
+	"context"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/pkg/errors"
+)
+
+// DefaultRetryMaxAttempts is the default value for RetryPolicy.MaxAttempts.
+const DefaultRetryMaxAttempts = 3
+
+// deadliner is implemented by connections which requests could be limited with deadline (like net.Conn).
+type deadliner interface {
+	SetDeadline(t time.Time) error
+}
+
+// RetryPolicy configures ConnPool.DoWithRetry.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts (including the first one).
+	//
+	// Default is DefaultRetryMaxAttempts.
+	MaxAttempts int
+
+	// PerTryTimeout limits the duration of one attempt: both waiting for the connection and the request itself.
+	// The request is limited with the connection deadline (see net.Conn.SetDeadline): fn should respect it.
+	// Pool[T] requests are limited only if T implements SetDeadline method.
+	//
+	// Attempts are limited only by the context by default.
+	PerTryTimeout time.Duration
+
+	// Retryable reports whether the request failed with the error passed should be retried.
+	//
+	// By default each error not classified as ErrorClassRequest (see Config.ErrorClassifier) is retried.
+	Retryable func(err error) bool
+
+	// InitialBackoffInterval configures InitialInterval for ExponentialBackOff algorithm used between attempts.
+	// See https://godoc.org/github.com/cenkalti/backoff#ExponentialBackOff for more info.
+	//
+	// Default is Config.InitialBackoffInterval.
+	InitialBackoffInterval time.Duration
+
+	// MaxBackoffInterval configures MaxInterval for ExponentialBackOff algorithm used between attempts.
+	// See https://godoc.org/github.com/cenkalti/backoff#ExponentialBackOff for more info.
+	//
+	// Default is Config.MaxBackoffInterval.
+	MaxBackoffInterval time.Duration
+}
+
+func (rp RetryPolicy) withDefaults(classify ErrorClassifier, cfg Config) RetryPolicy {
+	if rp.MaxAttempts == 0 {
+		rp.MaxAttempts = DefaultRetryMaxAttempts
+	}
+
+	if rp.Retryable == nil {
+		rp.Retryable = func(err error) bool {
+			return classify(err) != ErrorClassRequest
+		}
+	}
+
+	if rp.InitialBackoffInterval == 0 {
+		rp.InitialBackoffInterval = cfg.InitialBackoffInterval
+	}
+
+	if rp.MaxBackoffInterval == 0 {
+		rp.MaxBackoffInterval = cfg.MaxBackoffInterval
+	}
+
+	return rp
+}
+
+func (rp RetryPolicy) newBackOff(cfg Config) backoff.BackOff {
+	bc := backoff.NewExponentialBackOff()
+	bc.InitialInterval = rp.InitialBackoffInterval
+	bc.MaxInterval = rp.MaxBackoffInterval
+	bc.MaxElapsedTime = 0
+	bc.Clock = cfg.Clock
+
+	bc.Reset() // required to re-setup config options
+
+	if cfg.backoffRandomizationFactor != nil {
+		// only for tests. Default backoff interval should be used in production
+		bc.RandomizationFactor = *cfg.backoffRandomizationFactor
+	}
+
+	return bc
+}
+
+func (p *pool[T]) DoWithRetry(ctx context.Context, policy RetryPolicy, fn func(T) error) error {
+	return p.doWithRetry(ctx, policy, func(r *resource[T]) error {
+		return fn(r.value)
+	})
+}
+
+func (p *pool[T]) doWithRetry(ctx context.Context, policy RetryPolicy, fn func(*resource[T]) error) error {
+	policy = policy.withDefaults(p.classify, p.serversConfig())
+
+	var (
+		bOff   = policy.newBackOff(p.cfg)
+		failed = map[connectionProvider[T]]bool{}
+	)
+
+	for attempt := 1; ; attempt++ {
+		s, err := p.tryDo(ctx, policy, failed, fn)
+		switch {
+		case err == nil:
+			return nil
+		case err == ErrNoServersRegistered, ctx.Err() != nil, attempt >= policy.MaxAttempts:
+			return err
+		case s != nil && !policy.Retryable(err):
+			return err
+		}
+
+		if s != nil {
+			failed[s] = true
+		}
+
+		if !p.budget.withdraw() {
+			p.log.info("request failed: retry budget exhausted", errorField(err))
+			return errors.Wrap(ErrRetryBudgetExhausted, err.Error())
+		}
+
+		waitFor := bOff.NextBackOff()
+		p.log.info("request failed, retrying", errorField(err), retryAfterField(waitFor))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-p.cfg.Clock.After(waitFor):
+		}
+	}
+}
+
+// tryDo makes one attempt of DoWithRetry.
+// Servers from failed set are used only if other servers can't return the connection.
+//
+// Server used during the attempt is returned (nil is returned if the connection wasn't opened).
+func (p *pool[T]) tryDo(ctx context.Context, policy RetryPolicy, failed map[connectionProvider[T]]bool,
+	fn func(*resource[T]) error) (connectionProvider[T], error) {
+
+	if policy.PerTryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.PerTryTimeout)
+		defer cancel()
+	}
+
+	r, err := p.openConnBlock(ctx, failed)
+	if err != nil {
+		return nil, err
+	}
+
+	// XXX: serversByAddr is modified only during initialization: p.mu isn't required
+	s := p.serversByAddr[r.ServerAddr()]
+
+	return s, p.do(r, func(r *resource[T]) error {
+		dl, ok := interface{}(r.value).(deadliner)
+		if policy.PerTryTimeout == 0 || !ok {
+			return fn(r)
+		}
+
+		deadline, _ := ctx.Deadline()
+		dl.SetDeadline(deadline) // nolint:errcheck
+
+		err := fn(r)
+
+		dl.SetDeadline(time.Time{}) // nolint:errcheck
+		return err
+	})
+}
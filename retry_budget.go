@@ -0,0 +1,123 @@
+package goconnpool
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultRetryBudgetRatio is the default value for RetryBudget.Ratio config variable.
+	DefaultRetryBudgetRatio = 0.1
+
+	// DefaultRetryBudgetMaxTokens is the default value for RetryBudget.MaxTokens config variable.
+	DefaultRetryBudgetMaxTokens = 10
+)
+
+// ErrRetryBudgetExhausted is returned (wrapped, use errors.Cause) when the retry was suppressed because
+// the retry budget is exhausted.
+var ErrRetryBudgetExhausted = fmt.Errorf("retry budget exhausted")
+
+// RetryBudget limits the number of retries as a fraction of successful requests (like Finagle or gRPC do):
+// retries multiply the load when servers are degraded.
+//
+// Budget is a token bucket: each successful request adds Ratio tokens into the bucket and each retry takes
+// one token. Retry is suppressed if there is no token in the bucket.
+//
+// Retries made by ConnPool.DoWithRetry and re-attempts made by ConnPool.OpenConn blocking loop after
+// server failures are limited (waits for ratelimited servers aren't retries).
+// Successful ConnPool.Do* requests and connections returned by ConnPool.OpenConn* calls are counted as
+// successful requests.
+type RetryBudget struct {
+	// Ratio is the number of tokens added into the bucket by each successful request.
+	// Negative values are rejected by Config.Validate.
+	//
+	// Default is DefaultRetryBudgetRatio.
+	Ratio float64
+
+	// MaxTokens is the bucket capacity. The bucket is full initially.
+	// Negative values are rejected by Config.Validate.
+	//
+	// Default is DefaultRetryBudgetMaxTokens.
+	MaxTokens float64
+}
+
+func (b *RetryBudget) validate() error {
+	if b == nil {
+		return nil
+	}
+
+	if b.Ratio < 0 {
+		return errors.Errorf("RetryBudget.Ratio should not be negative (got %v)", b.Ratio)
+	}
+
+	if b.MaxTokens < 0 {
+		return errors.Errorf("RetryBudget.MaxTokens should not be negative (got %v)", b.MaxTokens)
+	}
+
+	return nil
+}
+
+type retryBudget struct {
+	mu sync.Mutex
+
+	tokens    float64
+	maxTokens float64
+	ratio     float64
+}
+
+func newRetryBudget(cfg *RetryBudget) *retryBudget {
+	if cfg == nil {
+		return nil
+	}
+
+	b := &retryBudget{
+		maxTokens: cfg.MaxTokens,
+		ratio:     cfg.Ratio,
+	}
+
+	// negative values are rejected by Config.Validate: defaults are used if the config wasn't validated
+	if b.maxTokens <= 0 {
+		b.maxTokens = DefaultRetryBudgetMaxTokens
+	}
+
+	if b.ratio <= 0 {
+		b.ratio = DefaultRetryBudgetRatio
+	}
+
+	b.tokens = b.maxTokens
+	return b
+}
+
+// deposit is called on each successful request.
+func (b *retryBudget) deposit() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += b.ratio
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
+
+// withdraw is called before each retry. Retry is allowed only if true is returned.
+func (b *retryBudget) withdraw() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
@@ -0,0 +1,131 @@
+package goconnpool
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func TestRetryBudget(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	var b *retryBudget
+	ass.True(b.withdraw()) // budget isn't configured
+	b.deposit()
+
+	b = newRetryBudget(&RetryBudget{MaxTokens: 2, Ratio: 0.5})
+	ass.True(b.withdraw())
+	ass.True(b.withdraw())
+	ass.False(b.withdraw())
+
+	b.deposit()
+	ass.False(b.withdraw())
+
+	b.deposit()
+	ass.True(b.withdraw())
+	ass.False(b.withdraw())
+
+	for i := 0; i < 10; i++ {
+		b.deposit()
+	}
+
+	ass.True(b.withdraw())
+	ass.True(b.withdraw())
+	ass.False(b.withdraw())
+
+	b = newRetryBudget(&RetryBudget{})
+	ass.Equal(float64(DefaultRetryBudgetMaxTokens), b.tokens)
+	ass.Equal(DefaultRetryBudgetRatio, b.ratio)
+
+	// config wasn't validated: defaults are used
+	b = newRetryBudget(&RetryBudget{MaxTokens: -1, Ratio: -1})
+	ass.Equal(float64(DefaultRetryBudgetMaxTokens), b.tokens)
+	ass.Equal(DefaultRetryBudgetRatio, b.ratio)
+
+	ass.EqualError(Config{RetryBudget: &RetryBudget{Ratio: -0.5}}.Validate(),
+		"invalid config: RetryBudget.Ratio should not be negative (got -0.5)")
+	ass.EqualError(Config{RetryBudget: &RetryBudget{MaxTokens: -1}}.Validate(),
+		"invalid config: RetryBudget.MaxTokens should not be negative (got -1)")
+	ass.NoError(Config{RetryBudget: &RetryBudget{}}.Validate())
+}
+
+func TestRetryBudgetExhausted(t *testing.T) {
+	t.Parallel()
+
+	t.Run("do_with_retry", func(t *testing.T) {
+		ass := require.New(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		dialer := NewMockDialer(ctrl)
+		dialer.EXPECT().Dial(gomock.Any(), "a").Return(newTestClosableConn(ctrl, true), nil)
+		dialer.EXPECT().Dial(gomock.Any(), "b").Return(newTestClosableConn(ctrl, true), nil)
+
+		p := newTestRetryPool(t, dialer)
+		p.budget = newRetryBudget(&RetryBudget{MaxTokens: 1})
+
+		var n int
+		err := p.DoWithRetry(context.Background(), RetryPolicy{
+			MaxAttempts:            10,
+			InitialBackoffInterval: time.Millisecond,
+			MaxBackoffInterval:     time.Millisecond,
+		}, func(cn Conn) error {
+			n++
+			return errTestBroken
+		})
+
+		ass.Equal(ErrRetryBudgetExhausted, errors.Cause(err))
+		ass.Equal(2, n)
+	})
+
+	t.Run("open_conn", func(t *testing.T) {
+		ass := require.New(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		p := newTestRetryPool(t, NewMockDialer(ctrl))
+		p.budget = newRetryBudget(&RetryBudget{MaxTokens: 1})
+
+		ass.NoError(p.SetServerMode("a", ServerModeDown))
+		ass.NoError(p.SetServerMode("b", ServerModeDown))
+
+		_, err := p.OpenConn(context.Background())
+		ass.Equal(ErrRetryBudgetExhausted, errors.Cause(err))
+	})
+
+	t.Run("open_conn_ratelimited", func(t *testing.T) {
+		ass := require.New(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		dialer := NewMockDialer(ctrl)
+		dialer.EXPECT().Dial(gomock.Any(), "a").Return(newTestClosableConn(ctrl, false), nil)
+
+		p := newConnPool(Config{
+			MaxRPS:      math.MaxInt32,
+			Dialer:      dialer,
+			Logger:      testLogger{t: t},
+			RetryBudget: &RetryBudget{MaxTokens: 1, Ratio: 0.01},
+		})
+		ass.NoError(p.RegisterServer("a"))
+
+		cn, err := p.OpenConn(context.Background())
+		ass.NoError(err)
+
+		// the only connection is borrowed: OpenConn waits several retry intervals
+		go func() {
+			time.Sleep(3 * defaultRetryTimeout)
+			cn.ReturnToPool() // nolint:errcheck
+		}()
+
+		cn, err = p.OpenConn(context.Background())
+		ass.NoError(err)
+		ass.NoError(cn.ReturnToPool())
+	})
+}
@@ -0,0 +1,119 @@
+package goconnpool
+
+import (
+	"context"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func newTestRetryPool(t *testing.T, dialer Dialer) *connPool {
+	p := newConnPool(Config{
+		MaxRPS:                 math.MaxInt32,
+		MaxConnsPerServer:      2,
+		InitialBackoffInterval: time.Hour,
+		MaxBackoffInterval:     time.Hour,
+		Dialer:                 dialer,
+		Logger:                 testLogger{t: t},
+		ErrorClassifier:        testErrorClassifier,
+	})
+
+	require.NoError(t, p.RegisterServer("a"))
+	require.NoError(t, p.RegisterServer("b"))
+
+	return p
+}
+
+func TestDoWithRetry(t *testing.T) {
+	t.Parallel()
+
+	policy := RetryPolicy{
+		InitialBackoffInterval: time.Millisecond,
+		MaxBackoffInterval:     time.Millisecond,
+	}
+
+	t.Run("failed_server_excluded", func(t *testing.T) {
+		ass := require.New(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		dialer := NewMockDialer(ctrl)
+		dialer.EXPECT().Dial(gomock.Any(), "a").Return(newTestClosableConn(ctrl, true), nil)
+		dialer.EXPECT().Dial(gomock.Any(), "b").Return(newTestClosableConn(ctrl, false), nil)
+
+		p := newTestRetryPool(t, dialer)
+
+		var addrs []string
+		err := p.DoWithRetry(context.Background(), policy, func(cn Conn) error {
+			addrs = append(addrs, cn.ServerAddr())
+			if len(addrs) > 1 {
+				return nil
+			}
+
+			// next server in round-robin order is the failed one
+			ass.NoError(p.Do(context.Background(), func(cn Conn) error {
+				ass.Equal("b", cn.ServerAddr())
+				return nil
+			}))
+
+			return errTestBroken
+		})
+
+		ass.NoError(err)
+		ass.Equal([]string{"a", "b"}, addrs)
+	})
+
+	t.Run("attempts_exceeded", func(t *testing.T) {
+		ass := require.New(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		dialer := NewMockDialer(ctrl)
+		dialer.EXPECT().Dial(gomock.Any(), "a").DoAndReturn(func(context.Context, string) (net.Conn, error) {
+			return newTestClosableConn(ctrl, true), nil
+		}).Times(2)
+		dialer.EXPECT().Dial(gomock.Any(), "b").Return(newTestClosableConn(ctrl, true), nil)
+
+		p := newTestRetryPool(t, dialer)
+
+		var addrs []string
+		err := p.DoWithRetry(context.Background(), policy, func(cn Conn) error {
+			addrs = append(addrs, cn.ServerAddr())
+			return errTestBroken
+		})
+
+		ass.Equal(errTestBroken, err)
+		ass.Equal([]string{"a", "b", "a"}, addrs)
+	})
+
+	t.Run("not_retryable", func(t *testing.T) {
+		ass := require.New(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		dialer := NewMockDialer(ctrl)
+		dialer.EXPECT().Dial(gomock.Any(), "a").Return(newTestClosableConn(ctrl, false), nil)
+
+		p := newTestRetryPool(t, dialer)
+
+		var n int
+		err := p.DoWithRetry(context.Background(), policy, func(cn Conn) error {
+			n++
+			return errTestRequest
+		})
+
+		ass.Equal(errTestRequest, err)
+		ass.Equal(1, n)
+		ass.Equal(1, p.Stats().Servers[0].IdleConns)
+	})
+
+	t.Run("no_servers", func(t *testing.T) {
+		p := newConnPool(Config{})
+		err := p.DoWithRetry(context.Background(), policy, func(cn Conn) error { return nil })
+		require.Equal(t, ErrNoServersRegistered, err)
+	})
+}
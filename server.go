@@ -3,20 +3,29 @@ package goconnpool
 import (
 	"context"
 	"fmt"
-	"net"
+	"runtime"
+	"runtime/debug"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cenkalti/backoff"
 	"github.com/pkg/errors"
 )
 
-type connectionProvider interface {
-	getConnection(ctx context.Context) (Conn, error)
+type connectionProvider[T any] interface {
+	getConnection(ctx context.Context) (*resource[T], error)
 	retryTimeout() time.Duration
+	stats() ServerStats
+	setMode(mode ServerMode)
+	updateConfig(cfg Config, f Factory[T])
 }
 
-type server struct {
+// defaultRetryTimeout is used when the server can't say when the connection could be opened.
+const defaultRetryTimeout = 100 * time.Millisecond // TODO: move into config
+
+type server[T any] struct {
 	mu sync.Mutex
 
 	addr           string
@@ -25,17 +34,31 @@ type server struct {
 	nOpenedConns int
 	maxConns     int
 	openedConns  deck
+	borrowed     map[uint64]*borrowInfo
+
+	maxStreams  int
+	sharedConns []*sharedConn[T]
+
+	leakThreshold time.Duration
+	limits        ServerLimits
 
 	reqDuration time.Duration
 	lastUsage   time.Time
 
-	dialer Dialer
+	factory Factory[T]
 
 	bOff        backoff.BackOff
 	nextBackoff time.Time
 	down        bool
+	mode        ServerMode
+
+	nDials         uint64
+	nDialErrors    uint64
+	nRatelimitHits uint64
 
 	clock Clock
+	hooks *Hooks
+	log   logger
 }
 
 var (
@@ -43,11 +66,7 @@ var (
 	errServerIsDown = fmt.Errorf("server is down")
 )
 
-func newServerWrapper(addr string, cfg Config) connectionProvider {
-	return newServer(addr, cfg)
-}
-
-func newServer(addr string, cfg Config) *server {
+func newServerBackOff(cfg Config) backoff.BackOff {
 	bc := backoff.NewExponentialBackOff()
 	bc.InitialInterval = cfg.InitialBackoffInterval
 	bc.MaxInterval = cfg.MaxBackoffInterval
@@ -61,21 +80,34 @@ func newServer(addr string, cfg Config) *server {
 		bc.RandomizationFactor = *cfg.backoffRandomizationFactor
 	}
 
-	return &server{
+	return bc
+}
+
+func newServer[T any](addr string, cfg Config, f Factory[T]) *server[T] {
+	return &server[T]{
 		addr:     addr,
 		maxConns: cfg.MaxConnsPerServer,
-		dialer:   cfg.Dialer,
-		bOff:     bc,
+
+		maxStreams: cfg.MaxStreamsPerConn,
+
+		borrowed: map[uint64]*borrowInfo{},
+		factory:  f,
+		bOff:     newServerBackOff(cfg),
+		mode:     ServerModeAuto,
 
 		connectTimeout: cfg.ConnectTimeout,
+		leakThreshold:  cfg.LeakThreshold,
+		limits:         newServerLimits(cfg),
 
 		reqDuration: time.Duration(1000000.0/float64(cfg.MaxRPS)) * time.Microsecond,
 
 		clock: cfg.Clock,
+		hooks: cfg.Hooks,
+		log:   logger{l: cfg.Logger},
 	}
 }
 
-func (s *server) updateLastUsage() bool {
+func (s *server[T]) updateLastUsage() bool {
 	// XXX: Function should be called under mutex
 
 	if s.getRatelimitTimeout() > 0 {
@@ -86,10 +118,15 @@ func (s *server) updateLastUsage() bool {
 	return true
 }
 
-func (s *server) retryTimeout() time.Duration {
+func (s *server[T]) retryTimeout() time.Duration {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.mode != ServerModeAuto {
+		// server could be enabled manually only
+		return defaultRetryTimeout
+	}
+
 	var waitFor time.Duration
 	if s.down {
 		waitFor = s.getDownTimeout()
@@ -99,15 +136,15 @@ func (s *server) retryTimeout() time.Duration {
 		waitFor = s.getRatelimitTimeout()
 	}
 
-	if waitFor == 0 && s.nOpenedConns >= s.maxConns {
+	if waitFor == 0 && s.nOpenedConns >= s.maxConns && !s.hasFreeStreams() {
 		// too many opened connections: can't open connection right now
-		waitFor = 100 * time.Millisecond // TODO: move into config
+		waitFor = defaultRetryTimeout
 	}
 
 	return waitFor
 }
 
-func (s *server) getDownTimeout() time.Duration {
+func (s *server[T]) getDownTimeout() time.Duration {
 	waitFor := s.clock.Since(s.nextBackoff)
 	if waitFor < 0 {
 		waitFor *= time.Duration(-1)
@@ -117,7 +154,7 @@ func (s *server) getDownTimeout() time.Duration {
 	return 0
 }
 
-func (s *server) getRatelimitTimeout() time.Duration {
+func (s *server[T]) getRatelimitTimeout() time.Duration {
 	waitFor := s.clock.Since(s.lastUsage) - s.reqDuration
 	if waitFor < 0 {
 		waitFor *= time.Duration(-1)
@@ -127,19 +164,22 @@ func (s *server) getRatelimitTimeout() time.Duration {
 	return 0
 }
 
-func (s *server) makeConnection(ctx context.Context) (net.Conn, error) {
+func (s *server[T]) makeConnection(ctx context.Context) (T, error) {
 	var (
-		cn  net.Conn
+		cn  T
 		err error
 	)
 
 	// Trying to establish connection
-	ctx, cancel := context.WithTimeout(ctx, s.connectTimeout)
+	ctx, cancel := context.WithTimeout(withClock(ctx, s.clock), s.connectTimeout)
 	defer cancel() // required to release context resources in case if ready chan was closed before timeout
 
+	// XXX: factory could be changed by updateConfig after timeout: don't access s.factory in the goroutine
+	f := s.factory
+
 	ready := make(chan struct{})
 	go func() {
-		cn, err = s.dialer.Dial(ctx, s.addr)
+		cn, err = f.Create(ctx, s.addr)
 		close(ready)
 	}()
 
@@ -150,72 +190,393 @@ func (s *server) makeConnection(ctx context.Context) (net.Conn, error) {
 		case <-ready:
 			return cn, err
 		default:
-			return nil, errors.WithStack(fmt.Errorf("can't dial to %s: timeout", s.addr))
+			var zero T
+			return zero, errors.WithStack(fmt.Errorf("can't dial to %s: timeout", s.addr))
 		}
 	case <-ready:
 		return cn, err
 	}
 }
 
-func (s *server) getConnection(ctx context.Context) (Conn, error) {
+func (s *server[T]) getConnection(ctx context.Context) (*resource[T], error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.checkLeaks()
+
+	if s.mode != ServerModeAuto {
+		s.log.debug("connection request rejected", serverField(s.addr), modeField(s.mode))
+		return nil, errors.Wrapf(errServerIsDown, "server %s is in %q mode", s.addr, s.mode)
+	}
+
 	if !s.updateLastUsage() {
-		return nil, errors.Wrap(errRatelimit, "too frequent request")
+		return nil, s.ratelimited(errors.Wrap(errRatelimit, "too frequent request"))
 	}
 
-	if s.openedConns.size() > 0 {
-		return s.wrapServerConn(s.openedConns.pop().(net.Conn)), nil
+	if s.maxStreams > 1 {
+		if sc := s.findSharedConn(); sc != nil {
+			return s.lease(sc), nil
+		}
+	}
+
+	for s.openedConns.size() > 0 {
+		idle := s.openedConns.pop().(idleConn[T])
+		if err := s.factory.Validate(idle.cn); err != nil {
+			s.log.debug("idle connection is invalid", serverField(s.addr), connIDField(idle.id), errorField(err))
+			s.closeConn(idle.cn, idle.id) // nolint:errcheck
+			continue
+		}
+
+		s.log.debug("connection reused", serverField(s.addr), connIDField(idle.id))
+		return s.borrow(idle.cn, idle.id), nil
 	}
 
 	if s.nOpenedConns >= s.maxConns {
-		return nil, errors.Wrap(errRatelimit, "too many opened connections")
+		return nil, s.ratelimited(errors.Wrap(errRatelimit, "too many opened connections"))
 	}
 
 	if s.down {
 		waitFor := s.getDownTimeout()
 		if waitFor > 0 {
 			// prevent too frequent connects here
-			return nil, errors.Wrapf(errRatelimit, "retry after %s", waitFor)
+			return nil, s.ratelimited(errors.Wrapf(errRatelimit, "retry after %s", waitFor))
 		}
 	}
 
+	s.nDials++
+	s.hooks.dialStart(s.addr)
+
+	dialStarted := s.clock.Now()
 	cn, err := s.makeConnection(ctx)
+	s.hooks.dialDone(s.addr, s.clock.Since(dialStarted), err)
+
 	if err != nil {
-		s.down = true
-		waitFor := s.bOff.NextBackOff()
-		s.nextBackoff = s.clock.Now().Add(waitFor)
+		s.nDialErrors++
+		waitFor := s.markDown(err)
+
+		s.log.error("can't establish connection", serverField(s.addr), errorField(err), retryAfterField(waitFor))
+
 		return nil, errors.Wrapf(errServerIsDown,
 			"can't establish connection to %s: %s; retry after %s", s.addr, err, waitFor)
 	}
 
 	s.nOpenedConns++
+	s.markUp()
+
+	id := atomic.AddUint64(&lastConnID, 1)
+	s.log.debug("connection established", serverField(s.addr), connIDField(id))
+
+	if s.maxStreams > 1 {
+		sc := &sharedConn[T]{cn: cn, id: id}
+		s.sharedConns = append(s.sharedConns, sc)
+		return s.lease(sc), nil
+	}
+
+	return s.borrow(cn, id), nil
+}
 
+// markDown marks the server down until the backoff interval passed.
+func (s *server[T]) markDown(err error) time.Duration {
+	// XXX: Function should be called under mutex
+
+	waitFor := s.bOff.NextBackOff()
+	s.nextBackoff = s.clock.Now().Add(waitFor)
+
+	if !s.down {
+		s.down = true
+		s.hooks.serverDown(s.addr, err, waitFor)
+	}
+
+	return waitFor
+}
+
+// markUp marks the server up after the successful dial or request (or when ServerModeAuto is set).
+func (s *server[T]) markUp() {
+	// XXX: Function should be called under mutex
+
+	if !s.down {
+		return
+	}
+
+	s.down = false
+	s.nextBackoff = time.Time{}
+	s.bOff.Reset()
+	s.hooks.serverUp(s.addr)
+	s.log.info("server is up", serverField(s.addr))
+}
+
+// reportSuccess is called when the request sent into the server connection was succeeded:
+// the server marked down by the previous failures is up again.
+func (s *server[T]) reportSuccess() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.markUp()
+}
+
+// reportFailure is called when the request sent into the server connection was failed because of the server.
+// Server is marked down and its idle connections are closed: they are likely broken too.
+func (s *server[T]) reportFailure(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	waitFor := s.markDown(err)
+	s.log.error("request to server failed", serverField(s.addr), errorField(err), retryAfterField(waitFor))
+
+	s.closeIdleConns()
+}
+
+// closeIdleConns closes connections not owned by the user.
+// Shared connections which are still leased are closed when all leases are released.
+func (s *server[T]) closeIdleConns() {
+	// XXX: Function should be called under mutex
+
+	for s.openedConns.size() > 0 {
+		idle := s.openedConns.pop().(idleConn[T])
+		s.closeConn(idle.cn, idle.id) // nolint:errcheck
+	}
+
+	shared := s.sharedConns
+	s.sharedConns = nil
+
+	for _, sc := range shared {
+		sc.closing = true
+		if sc.leases == 0 {
+			s.closeConn(sc.cn, sc.id) // nolint:errcheck
+		} else {
+			s.sharedConns = append(s.sharedConns, sc)
+		}
+	}
+}
+
+// updateConfig applies new limits, timeouts and backoff intervals. Factory isn't changed if f is nil.
+// Borrowed connections are kept; excess idle connections are closed if limits were shrunk.
+func (s *server[T]) updateConfig(cfg Config, f Factory[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if (s.maxStreams > 1) != (cfg.MaxStreamsPerConn > 1) {
+		// multiplexed mode is switched: idle connections of the previous mode shouldn't be reused
+		s.closeIdleConns()
+	}
+
+	s.maxConns = cfg.MaxConnsPerServer
+	s.maxStreams = cfg.MaxStreamsPerConn
+	s.connectTimeout = cfg.ConnectTimeout
+	s.reqDuration = time.Duration(1000000.0/float64(cfg.MaxRPS)) * time.Microsecond
+	if cfg.InitialBackoffInterval != s.limits.InitialBackoffInterval ||
+		cfg.MaxBackoffInterval != s.limits.MaxBackoffInterval {
+		// backoff progress of the server which is down is kept until intervals are changed
+		s.bOff = newServerBackOff(cfg)
+	}
+
+	s.limits = newServerLimits(cfg)
+
+	if f != nil {
+		s.factory = f
+	}
+
+	for s.nOpenedConns > s.maxConns && s.openedConns.size() > 0 {
+		idle := s.openedConns.pop().(idleConn[T])
+		s.closeConn(idle.cn, idle.id) // nolint:errcheck
+	}
+
+	for i := 0; i < len(s.sharedConns) && s.nOpenedConns > s.maxConns; {
+		if sc := s.sharedConns[i]; sc.leases == 0 {
+			s.closeSharedConn(sc) // nolint:errcheck
+			continue
+		}
+
+		i++
+	}
+
+	s.log.info("server config updated", serverField(s.addr))
+}
+
+func (s *server[T]) setMode(mode ServerMode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mode = mode
+	s.log.info("server mode changed", serverField(s.addr), modeField(mode))
+
+	switch mode {
+	case ServerModeAuto:
+		// server is marked up manually: forget about previous dial errors
+		s.markUp()
+	case ServerModeDrain:
+		s.closeIdleConns()
+	}
+}
+
+func (s *server[T]) closeConn(cn T, id uint64) error {
+	// XXX: Function should be called under mutex
+
+	s.nOpenedConns--
+
+	err := s.factory.Close(cn)
+	s.hooks.connClosed(s.addr, err)
+	s.log.debug("connection closed", serverField(s.addr), connIDField(id), errorField(err))
+
+	return err
+}
+
+func (s *server[T]) ratelimited(err error) error {
+	// XXX: Function should be called under mutex
+
+	s.nRatelimitHits++
+	s.hooks.ratelimited(s.addr, err)
+	s.log.debug("connection request ratelimited", serverField(s.addr), errorField(err))
+	return err
+}
+
+func (s *server[T]) stats() ServerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var nextRetry time.Time
 	if s.down {
-		s.down = false
-		s.bOff.Reset()
+		nextRetry = s.nextBackoff
+	}
+
+	borrowed := make([]BorrowedConnStats, 0, len(s.borrowed))
+	for id, b := range s.borrowed {
+		borrowed = append(borrowed, BorrowedConnStats{
+			ID:         id,
+			BorrowedAt: b.since,
+			Stack:      b.stack,
+		})
+	}
+
+	sort.Slice(borrowed, func(i, j int) bool {
+		return borrowed[i].ID < borrowed[j].ID
+	})
+
+	idle, streams := s.openedConns.size(), 0
+	for _, sc := range s.sharedConns {
+		if sc.leases == 0 {
+			idle++
+		}
+
+		streams += sc.leases
+	}
+
+	return ServerStats{
+		Address:       s.addr,
+		Up:            !s.down && s.mode == ServerModeAuto,
+		Mode:          s.mode,
+		NextRetry:     nextRetry,
+		Borrowed:      borrowed,
+		OpenConns:     s.nOpenedConns,
+		IdleConns:     idle,
+		InUseConns:    s.nOpenedConns - idle,
+		ActiveStreams: streams,
+		Limits:        s.limits,
+		Dials:         s.nDials,
+		DialErrors:    s.nDialErrors,
+		RatelimitHits: s.nRatelimitHits,
+	}
+}
+
+// lastConnID is used to generate unique connection identifiers.
+var lastConnID uint64
+
+// borrowInfo holds information about the connection owned by the user.
+type borrowInfo struct {
+	since time.Time
+	stack string // filled only if leaks detection is enabled
+
+	leakReported bool
+}
+
+func (s *server[T]) checkLeaks() {
+	// XXX: Function should be called under mutex
+
+	if s.leakThreshold <= 0 {
+		return
+	}
+
+	for id, b := range s.borrowed {
+		heldFor := s.clock.Since(b.since)
+		if b.leakReported || heldFor < s.leakThreshold {
+			continue
+		}
+
+		b.leakReported = true
+		s.hooks.connLeaked(s.addr, id, heldFor, b.stack)
+		s.log.error("connection is held too long: possible leak",
+			serverField(s.addr), connIDField(id), heldForField(heldFor), stackField(b.stack))
 	}
+}
 
-	return s.wrapServerConn(cn), nil
+// idleConn is the connection returned into pool.
+type idleConn[T any] struct {
+	cn T
+	id uint64
 }
 
-type serverConn struct {
-	net.Conn
-	s *server
+// resource is the connection owned by the user.
+type resource[T any] struct {
+	value  T
+	s      *server[T]
+	id     uint64
+	shared *sharedConn[T] // set only in multiplexed mode
 
 	closed bool
 	inPool bool
 }
 
-func (s *server) wrapServerConn(cn net.Conn) Conn {
-	return &serverConn{
-		Conn: cn,
-		s:    s,
+func (s *server[T]) borrow(cn T, id uint64) *resource[T] {
+	// XXX: Function should be called under mutex
+
+	b := &borrowInfo{
+		since: s.clock.Now(),
+	}
+
+	s.borrowed[id] = b
+	r := &resource[T]{
+		value: cn,
+		s:     s,
+		id:    id,
+	}
+
+	if s.leakThreshold > 0 {
+		b.stack = string(debug.Stack())
+		runtime.SetFinalizer(r, (*resource[T]).reclaim)
+	}
+
+	return r
+}
+
+// reclaim releases the slot of the connection which was garbage collected without Close() or
+// ReturnToPool() call.
+func (cn *resource[T]) reclaim() {
+	cn.s.mu.Lock()
+	defer cn.s.mu.Unlock()
+
+	if cn.closed || cn.inPool {
+		return
+	}
+
+	var stack string
+	if b, ok := cn.s.borrowed[cn.id]; ok {
+		stack = b.stack
+	}
+
+	delete(cn.s.borrowed, cn.id)
+	cn.s.log.error("connection was garbage collected without Close() or ReturnToPool() call",
+		serverField(cn.s.addr), connIDField(cn.id), stackField(stack))
+
+	cn.closed = true
+	if cn.shared != nil {
+		cn.s.release(cn.shared, true) // nolint:errcheck
+		return
 	}
+
+	cn.s.closeConn(cn.value, cn.id) // nolint:errcheck
 }
 
-func (cn *serverConn) checkCouldBeReturned() error {
+func (cn *resource[T]) checkCouldBeReturned() error {
 	if cn.closed {
 		return errors.New("connection already closed")
 	}
@@ -227,7 +588,7 @@ func (cn *serverConn) checkCouldBeReturned() error {
 	return nil
 }
 
-func (cn *serverConn) ReturnToPool() error {
+func (cn *resource[T]) ReturnToPool() error {
 	cn.s.mu.Lock()
 	defer cn.s.mu.Unlock()
 
@@ -236,12 +597,30 @@ func (cn *serverConn) ReturnToPool() error {
 	}
 
 	cn.inPool = true
-	cn.s.openedConns.push(cn.Conn)
+	delete(cn.s.borrowed, cn.id)
+	runtime.SetFinalizer(cn, nil)
+
+	// connection could be marked broken by the ConnWrapper
+	b, ok := any(cn.value).(brokenConn)
+	broken := ok && b.broken()
+
+	if cn.shared != nil {
+		return errors.WithStack(cn.s.release(cn.shared, broken))
+	}
+
+	if cn.s.mode == ServerModeDrain || broken {
+		// connection shouldn't be reused
+		return errors.WithStack(cn.s.closeConn(cn.value, cn.id))
+	}
+
+	cn.s.openedConns.push(idleConn[T]{cn: cn.value, id: cn.id})
+	cn.s.hooks.release(cn.s.addr)
+	cn.s.log.debug("connection returned into pool", serverField(cn.s.addr), connIDField(cn.id))
 
 	return nil
 }
 
-func (cn *serverConn) Close() error {
+func (cn *resource[T]) Close() error {
 	cn.s.mu.Lock()
 	defer cn.s.mu.Unlock()
 
@@ -249,12 +628,29 @@ func (cn *serverConn) Close() error {
 		return errors.WithStack(err)
 	}
 
-	cn.s.nOpenedConns--
 	cn.closed = true
+	delete(cn.s.borrowed, cn.id)
+	runtime.SetFinalizer(cn, nil)
+
+	if cn.shared != nil {
+		return errors.WithStack(cn.s.release(cn.shared, true))
+	}
+
+	return errors.WithStack(cn.s.closeConn(cn.value, cn.id))
+}
+
+func (cn *resource[T]) reportFailure(err error) {
+	cn.s.reportFailure(err)
+}
+
+func (cn *resource[T]) reportSuccess() {
+	cn.s.reportSuccess()
+}
 
-	return errors.WithStack(cn.Conn.Close())
+func (cn *resource[T]) Value() T {
+	return cn.value
 }
 
-func (cn *serverConn) OriginalConn() net.Conn {
-	return cn.Conn
+func (cn *resource[T]) ServerAddr() string {
+	return cn.s.addr
 }
@@ -1,5 +1,10 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: server.go
+// Source: github.com/derElektrobesen/goconnpool (interfaces: connectionProvider)
+//
+// Generated by this command:
+//
+//	mockgen -package=goconnpool -self_package=github.com/derElektrobesen/goconnpool -destination=server_mock_test.go github.com/derElektrobesen/goconnpool connectionProvider
+//
 
 // Package goconnpool is a generated GoMock package.
 package goconnpool
@@ -9,53 +14,96 @@ import (
 	reflect "reflect"
 	time "time"
 
-	gomock "github.com/golang/mock/gomock"
+	gomock "go.uber.org/mock/gomock"
 )
 
-// MockconnectionProvider is a mock of connectionProvider interface
-type MockconnectionProvider struct {
+// MockconnectionProvider is a mock of connectionProvider interface.
+type MockconnectionProvider[T any] struct {
 	ctrl     *gomock.Controller
-	recorder *MockconnectionProviderMockRecorder
+	recorder *MockconnectionProviderMockRecorder[T]
+	isgomock struct{}
 }
 
-// MockconnectionProviderMockRecorder is the mock recorder for MockconnectionProvider
-type MockconnectionProviderMockRecorder struct {
-	mock *MockconnectionProvider
+// MockconnectionProviderMockRecorder is the mock recorder for MockconnectionProvider.
+type MockconnectionProviderMockRecorder[T any] struct {
+	mock *MockconnectionProvider[T]
 }
 
-// NewMockconnectionProvider creates a new mock instance
-func NewMockconnectionProvider(ctrl *gomock.Controller) *MockconnectionProvider {
-	mock := &MockconnectionProvider{ctrl: ctrl}
-	mock.recorder = &MockconnectionProviderMockRecorder{mock}
+// NewMockconnectionProvider creates a new mock instance.
+func NewMockconnectionProvider[T any](ctrl *gomock.Controller) *MockconnectionProvider[T] {
+	mock := &MockconnectionProvider[T]{ctrl: ctrl}
+	mock.recorder = &MockconnectionProviderMockRecorder[T]{mock}
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
-func (m *MockconnectionProvider) EXPECT() *MockconnectionProviderMockRecorder {
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockconnectionProvider[T]) EXPECT() *MockconnectionProviderMockRecorder[T] {
 	return m.recorder
 }
 
-// getConnection mocks base method
-func (m *MockconnectionProvider) getConnection(ctx context.Context) (Conn, error) {
+// getConnection mocks base method.
+func (m *MockconnectionProvider[T]) getConnection(ctx context.Context) (*resource[T], error) {
+	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "getConnection", ctx)
-	ret0, _ := ret[0].(Conn)
+	ret0, _ := ret[0].(*resource[T])
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// getConnection indicates an expected call of getConnection
-func (mr *MockconnectionProviderMockRecorder) getConnection(ctx interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getConnection", reflect.TypeOf((*MockconnectionProvider)(nil).getConnection), ctx)
+// getConnection indicates an expected call of getConnection.
+func (mr *MockconnectionProviderMockRecorder[T]) getConnection(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getConnection", reflect.TypeOf((*MockconnectionProvider[T])(nil).getConnection), ctx)
 }
 
-// retryTimeout mocks base method
-func (m *MockconnectionProvider) retryTimeout() time.Duration {
+// retryTimeout mocks base method.
+func (m *MockconnectionProvider[T]) retryTimeout() time.Duration {
+	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "retryTimeout")
 	ret0, _ := ret[0].(time.Duration)
 	return ret0
 }
 
-// retryTimeout indicates an expected call of retryTimeout
-func (mr *MockconnectionProviderMockRecorder) retryTimeout() *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "retryTimeout", reflect.TypeOf((*MockconnectionProvider)(nil).retryTimeout))
+// retryTimeout indicates an expected call of retryTimeout.
+func (mr *MockconnectionProviderMockRecorder[T]) retryTimeout() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "retryTimeout", reflect.TypeOf((*MockconnectionProvider[T])(nil).retryTimeout))
+}
+
+// setMode mocks base method.
+func (m *MockconnectionProvider[T]) setMode(mode ServerMode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "setMode", mode)
+}
+
+// setMode indicates an expected call of setMode.
+func (mr *MockconnectionProviderMockRecorder[T]) setMode(mode any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setMode", reflect.TypeOf((*MockconnectionProvider[T])(nil).setMode), mode)
+}
+
+// stats mocks base method.
+func (m *MockconnectionProvider[T]) stats() ServerStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "stats")
+	ret0, _ := ret[0].(ServerStats)
+	return ret0
+}
+
+// stats indicates an expected call of stats.
+func (mr *MockconnectionProviderMockRecorder[T]) stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "stats", reflect.TypeOf((*MockconnectionProvider[T])(nil).stats))
+}
+
+// updateConfig mocks base method.
+func (m *MockconnectionProvider[T]) updateConfig(cfg Config, f Factory[T]) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "updateConfig", cfg, f)
+}
+
+// updateConfig indicates an expected call of updateConfig.
+func (mr *MockconnectionProviderMockRecorder[T]) updateConfig(cfg, f any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "updateConfig", reflect.TypeOf((*MockconnectionProvider[T])(nil).updateConfig), cfg, f)
 }
@@ -0,0 +1,80 @@
+package goconnpool
+
+import "time"
+
+// ServerOption overrides the pool configuration for one server (see ConnPool.RegisterServer).
+// Values which aren't overridden (or overridden with zero) fall back to the pool configuration.
+// Values are validated by RegisterServer: negative ones are rejected.
+// Options are created by Server* functions: With* functions of the package are dialer and connection middlewares.
+type ServerOption func(*Config)
+
+// ServerMaxConns overrides Config.MaxConnsPerServer.
+func ServerMaxConns(n int) ServerOption {
+	return func(cfg *Config) {
+		if n != 0 {
+			cfg.MaxConnsPerServer = n
+		}
+	}
+}
+
+// ServerMaxStreams overrides Config.MaxStreamsPerConn.
+func ServerMaxStreams(n int) ServerOption {
+	return func(cfg *Config) {
+		if n != 0 {
+			cfg.MaxStreamsPerConn = n
+		}
+	}
+}
+
+// ServerMaxRPS overrides Config.MaxRPS.
+func ServerMaxRPS(n int) ServerOption {
+	return func(cfg *Config) {
+		if n != 0 {
+			cfg.MaxRPS = n
+		}
+	}
+}
+
+// ServerConnectTimeout overrides Config.ConnectTimeout.
+func ServerConnectTimeout(timeout time.Duration) ServerOption {
+	return func(cfg *Config) {
+		if timeout != 0 {
+			cfg.ConnectTimeout = timeout
+		}
+	}
+}
+
+// ServerBackoff overrides Config.InitialBackoffInterval and Config.MaxBackoffInterval.
+// Zero interval isn't overridden.
+func ServerBackoff(initial, max time.Duration) ServerOption {
+	return func(cfg *Config) {
+		if initial != 0 {
+			cfg.InitialBackoffInterval = initial
+		}
+
+		if max != 0 {
+			cfg.MaxBackoffInterval = max
+		}
+	}
+}
+
+// ServerLimits holds the effective configuration of the server.
+type ServerLimits struct {
+	MaxConns               int
+	MaxStreamsPerConn      int
+	MaxRPS                 int
+	ConnectTimeout         time.Duration
+	InitialBackoffInterval time.Duration
+	MaxBackoffInterval     time.Duration
+}
+
+func newServerLimits(cfg Config) ServerLimits {
+	return ServerLimits{
+		MaxConns:               cfg.MaxConnsPerServer,
+		MaxStreamsPerConn:      cfg.MaxStreamsPerConn,
+		MaxRPS:                 cfg.MaxRPS,
+		ConnectTimeout:         cfg.ConnectTimeout,
+		InitialBackoffInterval: cfg.InitialBackoffInterval,
+		MaxBackoffInterval:     cfg.MaxBackoffInterval,
+	}
+}
@@ -5,20 +5,22 @@ import (
 	"fmt"
 	"math"
 	net "net"
+	"reflect"
+	"runtime"
 	"testing"
 	"time"
 
 	"github.com/benbjohnson/clock"
-	gomock "github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
 )
 
 type testServer struct {
 	t   *testing.T
 	ass *require.Assertions
 
-	s   *server
+	s   *server[net.Conn]
 	cfg Config
 
 	ctrl *gomock.Controller
@@ -32,8 +34,7 @@ func newTestServer() testServer {
 }
 
 func (s testServer) withConfig(cfg Config) testServer {
-	var empty Config
-	if s.cfg != empty {
+	if !reflect.DeepEqual(s.cfg, Config{}) {
 		panic("call withConfig() before all modifications")
 	}
 
@@ -52,14 +53,14 @@ func (s testServer) withoutRateLimits() testServer {
 	return s
 }
 
-func (s testServer) getConnection() (Conn, error) {
+func (s testServer) getConnection() (*resource[net.Conn], error) {
 	s.t.Helper()
 
 	s.clockMock.Add(time.Second)
 	return s.s.getConnection(context.Background())
 }
 
-func (s testServer) getConnectionNoError() Conn {
+func (s testServer) getConnectionNoError() *resource[net.Conn] {
 	s.t.Helper()
 	cn, err := s.getConnection()
 	s.ass.NoError(err)
@@ -83,9 +84,10 @@ func (s testServer) wrap(cb func(s testServer)) func(t *testing.T) {
 
 		s.cfg.Clock = s.clockMock
 		s.cfg.Dialer = s.dialerMock
+		s.cfg.Logger = testLogger{t: t}
 		s.ctrl = ctrl
 
-		s.s = newServer("addr", s.cfg)
+		s.s = newServer[net.Conn]("addr", s.cfg, dialerFactory{d: s.cfg.Dialer})
 
 		cb(s)
 	}
@@ -208,18 +210,18 @@ func testConnsReuse(s testServer) {
 	s.ass.NoError(cn1.ReturnToPool())
 
 	cn := s.getConnectionNoError()
-	s.ass.Equal(cn1.OriginalConn(), cn.OriginalConn())
+	s.ass.Equal(cn1.Value(), cn.Value())
 
 	cn2 := s.getConnectionNoError()
 	s.ass.NoError(cn2.ReturnToPool())
 
 	cn = s.getConnectionNoError()
-	s.ass.Equal(cn1.OriginalConn(), cn.OriginalConn())
+	s.ass.Equal(cn1.Value(), cn.Value())
 
 	s.ass.NoError(cn.ReturnToPool())
 
 	cn = s.getConnectionNoError()
-	s.ass.Equal(cn2.OriginalConn(), cn.OriginalConn())
+	s.ass.Equal(cn2.Value(), cn.Value())
 }
 
 func testBrokenConns(s testServer) {
@@ -315,7 +317,7 @@ func testServerIsDown(s testServer) {
 	gotCn, err := s.s.getConnection(ctx)
 	s.ass.NoError(err)
 
-	_, ok := gotCn.OriginalConn().(*net.IPConn)
+	_, ok := gotCn.Value().(*net.IPConn)
 	s.ass.True(ok)
 
 	// Check backoff correctly updated when server was up
@@ -352,6 +354,311 @@ func testConnectionDoubleClose(s testServer) {
 	s.ass.Error(cn2.ReturnToPool())
 }
 
+func testServerStats(s testServer) {
+	gomock.InOrder(
+		s.dialerMock.EXPECT().
+			Dial(gomock.Any(), gomock.Any()).
+			Return(nil, fmt.Errorf("xxx")),
+		s.dialerMock.EXPECT().
+			Dial(gomock.Any(), gomock.Any()).
+			Return(&net.IPConn{}, nil).
+			Times(2),
+	)
+
+	limits := ServerLimits{
+		MaxConns:               2,
+		MaxRPS:                 1,
+		ConnectTimeout:         24 * time.Hour,
+		InitialBackoffInterval: time.Minute,
+	}
+
+	_, err := s.getConnection()
+	s.ass.Equal(errServerIsDown, errors.Cause(err))
+	s.ass.Equal(ServerStats{
+		Address:    "addr",
+		Limits:     limits,
+		Mode:       ServerModeAuto,
+		NextRetry:  s.clockMock.Now().Add(time.Minute),
+		Borrowed:   []BorrowedConnStats{},
+		Dials:      1,
+		DialErrors: 1,
+	}, s.s.stats())
+
+	s.clockMock.Add(time.Minute) // backoff interval passed
+
+	cn1 := s.getConnectionNoError()
+	cn2 := s.getConnectionNoError()
+	s.ass.NoError(cn1.ReturnToPool())
+
+	_, err = s.s.getConnection(context.Background()) // too frequent request
+	s.ass.Equal(errRatelimit, errors.Cause(err))
+
+	s.ass.Equal(ServerStats{
+		Address: "addr",
+		Limits:  limits,
+		Up:      true,
+		Mode:    ServerModeAuto,
+		Borrowed: []BorrowedConnStats{
+			{ID: cn2.id, BorrowedAt: s.clockMock.Now()},
+		},
+		OpenConns:     2,
+		IdleConns:     1,
+		InUseConns:    1,
+		Dials:         3,
+		DialErrors:    1,
+		RatelimitHits: 1,
+	}, s.s.stats())
+
+	s.ass.Equal("addr", cn1.ServerAddr())
+}
+
+func testServerModes(s testServer) {
+	rec := &hooksRecorder{}
+	s.s.hooks = rec.hooks()
+
+	gomock.InOrder(
+		s.dialerMock.EXPECT().
+			Dial(gomock.Any(), gomock.Any()).
+			Return(nil, fmt.Errorf("xxx")),
+		s.dialerMock.EXPECT().
+			Dial(gomock.Any(), gomock.Any()).
+			DoAndReturn(s.newClosableTestConnFactory(nil, true)).
+			Times(2),
+	)
+
+	_, err := s.getConnection()
+	s.ass.Equal(errServerIsDown, errors.Cause(err))
+
+	// server is marked up manually: backoff interval is ignored
+	s.s.setMode(ServerModeAuto)
+	s.ass.True(s.s.stats().Up)
+	s.ass.Contains(rec.events, "up addr")
+
+	cn1 := s.getConnectionNoError()
+	cn2 := s.getConnectionNoError()
+	s.ass.NoError(cn1.ReturnToPool())
+
+	s.s.setMode(ServerModeDown)
+	_, err = s.getConnection()
+	s.ass.Equal(errServerIsDown, errors.Cause(err))
+	s.ass.Equal(defaultRetryTimeout, s.s.retryTimeout())
+	s.ass.Equal(2, s.s.stats().OpenConns) // connections are kept
+	s.ass.False(s.s.stats().Up)
+
+	s.s.setMode(ServerModeDrain)
+	s.ass.Equal(1, s.s.stats().OpenConns) // idle connection is closed
+
+	s.ass.NoError(cn2.ReturnToPool()) // borrowed connection is closed
+	s.ass.Error(cn2.ReturnToPool())
+	s.ass.Equal(0, s.s.stats().OpenConns)
+
+	_, err = s.getConnection()
+	s.ass.Equal(errServerIsDown, errors.Cause(err))
+}
+
+func testLeakDetection(s testServer) {
+	var leaks []string
+	s.s.hooks = &Hooks{
+		OnConnLeaked: func(addr string, connID uint64, heldFor time.Duration, stack string) {
+			s.ass.Contains(stack, "testLeakDetection")
+			leaks = append(leaks, fmt.Sprintf("%s %d %s", addr, connID, heldFor))
+		},
+	}
+
+	s.dialerMock.EXPECT().
+		Dial(gomock.Any(), gomock.Any()).
+		Return(&net.IPConn{}, nil).
+		Times(3)
+
+	cn1 := s.getConnectionNoError()
+	s.getConnectionNoError()
+
+	st := s.s.stats()
+	s.ass.Len(st.Borrowed, 2)
+	s.ass.Contains(st.Borrowed[0].Stack, "testLeakDetection")
+
+	s.ass.NoError(cn1.ReturnToPool())
+
+	s.clockMock.Add(time.Minute)
+	s.getConnectionNoError() // leak is reported here
+	s.getConnectionNoError() // leak isn't reported twice
+
+	s.ass.Equal([]string{
+		fmt.Sprintf("addr %d 1m1s", s.s.stats().Borrowed[1].ID),
+	}, leaks)
+}
+
+func testLeakReclaim(s testServer) {
+	s.dialerMock.EXPECT().
+		Dial(gomock.Any(), gomock.Any()).
+		DoAndReturn(s.newClosableTestConnFactory(nil, true))
+
+	s.getConnectionNoError() // connection is dropped without Close() call
+	s.ass.Equal(1, s.s.stats().OpenConns)
+
+	for i := 0; i < 100 && s.s.stats().OpenConns > 0; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	st := s.s.stats()
+	s.ass.Equal(0, st.OpenConns)
+	s.ass.Empty(st.Borrowed)
+}
+
+type hooksRecorder struct {
+	events []string
+}
+
+func (r *hooksRecorder) hooks() *Hooks {
+	record := func(format string, args ...interface{}) {
+		r.events = append(r.events, fmt.Sprintf(format, args...))
+	}
+
+	return &Hooks{
+		OnDialStart:   func(addr string) { record("dial_start %s", addr) },
+		OnDialDone:    func(addr string, dur time.Duration, err error) { record("dial_done %s %v", addr, err) },
+		OnServerDown:  func(addr string, err error, retryAfter time.Duration) { record("down %s %s", addr, retryAfter) },
+		OnServerUp:    func(addr string) { record("up %s", addr) },
+		OnAcquire:     func(addr string, wait time.Duration) { record("acquire %s %s", addr, wait) },
+		OnRelease:     func(addr string) { record("release %s", addr) },
+		OnRatelimited: func(addr string, err error) { record("ratelimited %s", addr) },
+		OnConnClosed:  func(addr string, err error) { record("closed %s %v", addr, err) },
+	}
+}
+
+func testServerHooks(s testServer) {
+	rec := &hooksRecorder{}
+	s.s.hooks = rec.hooks()
+
+	gomock.InOrder(
+		s.dialerMock.EXPECT().
+			Dial(gomock.Any(), gomock.Any()).
+			Return(nil, fmt.Errorf("xxx")).
+			Times(2),
+		s.dialerMock.EXPECT().
+			Dial(gomock.Any(), gomock.Any()).
+			DoAndReturn(s.newClosableTestConnFactory(nil, true)),
+	)
+
+	_, err := s.getConnection()
+	s.ass.Error(err)
+
+	_, err = s.s.getConnection(context.Background()) // backoff interval wasn't passed
+	s.ass.Error(err)
+
+	s.clockMock.Add(time.Minute)
+	_, err = s.s.getConnection(context.Background()) // server is still down
+	s.ass.Error(err)
+
+	s.clockMock.Add(time.Hour)
+	cn := s.getConnectionNoError()
+	s.ass.NoError(cn.ReturnToPool())
+
+	cn = s.getConnectionNoError()
+	s.ass.NoError(cn.Close())
+
+	s.ass.Equal([]string{
+		"dial_start addr",
+		"dial_done addr xxx",
+		"down addr 1m0s",
+		"ratelimited addr",
+		"dial_start addr",
+		"dial_done addr xxx",
+		"dial_start addr",
+		"dial_done addr <nil>",
+		"up addr",
+		"release addr",
+		"closed addr <nil>",
+	}, rec.events)
+}
+
+func testMultiplexedConns(s testServer) {
+	s.dialerMock.EXPECT().
+		Dial(gomock.Any(), gomock.Any()).
+		DoAndReturn(s.newClosableTestConnFactory(nil, true)).
+		Times(2)
+
+	cn1 := s.getConnectionNoError()
+	cn2 := s.getConnectionNoError()
+	s.ass.True(cn1.Value() == cn2.Value()) // the same connection is leased twice
+	s.ass.NotEqual(cn1.id, cn2.id)
+
+	_, err := s.getConnection()
+	s.ass.Equal(errRatelimit, errors.Cause(err)) // all streams are busy
+
+	st := s.s.stats()
+	s.ass.Equal(1, st.OpenConns)
+	s.ass.Equal(1, st.InUseConns)
+	s.ass.Equal(2, st.ActiveStreams)
+	s.ass.Len(st.Borrowed, 2)
+	s.ass.Equal(defaultRetryTimeout, s.s.retryTimeout())
+
+	s.ass.NoError(cn1.ReturnToPool())
+	s.ass.Equal(time.Duration(0), s.s.retryTimeout())
+
+	cn3 := s.getConnectionNoError()
+	s.ass.True(cn3.Value() == cn2.Value())
+
+	// connection is broken: it isn't leased anymore, but it is closed only when all leases are released
+	s.ass.NoError(cn2.Close())
+	s.ass.Equal(1, s.s.stats().OpenConns)
+	s.ass.Equal(1, s.s.stats().ActiveStreams)
+
+	_, err = s.getConnection()
+	s.ass.Equal(errRatelimit, errors.Cause(err))
+
+	s.ass.NoError(cn3.ReturnToPool())
+	s.ass.Error(cn3.Close())
+
+	st = s.s.stats()
+	s.ass.Equal(0, st.OpenConns)
+	s.ass.Equal(0, st.ActiveStreams)
+
+	// new connection is dialed, idle connection is closed in drain mode
+	cn4 := s.getConnectionNoError()
+	s.ass.False(cn4.Value() == cn1.Value())
+	s.ass.NoError(cn4.ReturnToPool())
+	s.ass.Equal(1, s.s.stats().IdleConns)
+
+	s.s.setMode(ServerModeDrain)
+	s.ass.Equal(0, s.s.stats().OpenConns)
+}
+
+func testServerUpdateConfig(s testServer) {
+	s.dialerMock.EXPECT().
+		Dial(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("xxx")).
+		Times(4)
+
+	_, err := s.s.getConnection(context.Background())
+	s.ass.Equal(errServerIsDown, errors.Cause(err))
+	s.ass.Equal(time.Minute, s.s.retryTimeout())
+
+	s.clockMock.Add(time.Minute)
+	_, err = s.s.getConnection(context.Background())
+	s.ass.Equal(errServerIsDown, errors.Cause(err))
+	s.ass.Equal(90*time.Second, s.s.retryTimeout())
+
+	// backoff intervals aren't changed: backoff progress is kept
+	s.s.updateConfig(s.cfg, nil)
+
+	s.clockMock.Add(90 * time.Second)
+	_, err = s.s.getConnection(context.Background())
+	s.ass.Equal(errServerIsDown, errors.Cause(err))
+	s.ass.Equal(135*time.Second, s.s.retryTimeout())
+
+	// new intervals are applied
+	cfg := s.cfg
+	cfg.InitialBackoffInterval = 2 * time.Minute
+	s.s.updateConfig(cfg, nil)
+
+	s.clockMock.Add(135 * time.Second)
+	_, err = s.s.getConnection(context.Background())
+	s.ass.Equal(errServerIsDown, errors.Cause(err))
+	s.ass.Equal(2*time.Minute, s.s.retryTimeout())
+}
+
 func TestServer(t *testing.T) {
 	t.Parallel()
 
@@ -405,6 +712,82 @@ func TestServer(t *testing.T) {
 			wrap(testServerIsDown),
 	)
 
+	t.Run("update_config",
+		newTestServer().
+			withConfig(Config{
+				InitialBackoffInterval:     time.Minute,
+				MaxBackoffInterval:         5 * time.Minute,
+				backoffRandomizationFactor: &backoffRandomizationFactor,
+			}).
+			withoutRateLimits().
+			withoutTimeouts().
+			wrap(testServerUpdateConfig),
+	)
+
+	t.Run("stats",
+		newTestServer().
+			withConfig(Config{
+				MaxRPS:                     1,
+				MaxConnsPerServer:          2,
+				InitialBackoffInterval:     time.Minute,
+				backoffRandomizationFactor: &backoffRandomizationFactor,
+			}).
+			withoutTimeouts().
+			wrap(testServerStats),
+	)
+
+	t.Run("modes",
+		newTestServer().
+			withConfig(Config{
+				InitialBackoffInterval: time.Hour,
+			}).
+			withoutRateLimits().
+			withoutTimeouts().
+			wrap(testServerModes),
+	)
+
+	t.Run("hooks",
+		newTestServer().
+			withConfig(Config{
+				InitialBackoffInterval:     time.Minute,
+				backoffRandomizationFactor: &backoffRandomizationFactor,
+			}).
+			withoutRateLimits().
+			withoutTimeouts().
+			wrap(testServerHooks),
+	)
+
+	t.Run("leak_detection",
+		newTestServer().
+			withConfig(Config{
+				LeakThreshold: time.Minute,
+			}).
+			withoutRateLimits().
+			withoutTimeouts().
+			wrap(testLeakDetection),
+	)
+
+	t.Run("leak_reclaim",
+		newTestServer().
+			withConfig(Config{
+				LeakThreshold: time.Minute,
+			}).
+			withoutRateLimits().
+			withoutTimeouts().
+			wrap(testLeakReclaim),
+	)
+
+	t.Run("multiplexed_conns",
+		newTestServer().
+			withConfig(Config{
+				MaxRPS:            math.MaxInt32,
+				MaxConnsPerServer: 1,
+				MaxStreamsPerConn: 2,
+			}).
+			withoutTimeouts().
+			wrap(testMultiplexedConns),
+	)
+
 	t.Run("connection_double_close",
 		newTestServer().
 			withoutRateLimits().
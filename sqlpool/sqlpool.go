@@ -0,0 +1,220 @@
+// Package sqlpool integrates goconnpool with database/sql for multi-host databases (like read replicas).
+//
+// Connector opens database connections to the replica chosen by goconnpool.Pool: round-robin balancing,
+// backoff of the broken replicas and per-replica ratelimits are applied.
+//
+//	f := sqlpool.NewFactory(drv, func(addr string) string {
+//		return "postgres://user@" + addr + "/db"
+//	})
+//
+//	p := goconnpool.NewPool[driver.Conn](cfg, f)
+//	p.RegisterServer("replica1:5432")
+//	p.RegisterServer("replica2:5432")
+//
+//	db := sql.OpenDB(sqlpool.NewConnector(p, drv))
+//
+// Dialer could be used with drivers which accept custom dial functions (like pgx or mysql) instead.
+package sqlpool
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+
+	"github.com/derElektrobesen/goconnpool"
+	"github.com/pkg/errors"
+)
+
+type factory struct {
+	d   driver.Driver
+	dsn func(addr string) string
+}
+
+// NewFactory creates goconnpool.Factory opening database connections using the driver passed.
+// dsn should return the data source name of the replica with address passed (registered in the pool).
+func NewFactory(d driver.Driver, dsn func(addr string) string) goconnpool.Factory[driver.Conn] {
+	return &factory{
+		d:   d,
+		dsn: dsn,
+	}
+}
+
+func (f *factory) Create(ctx context.Context, addr string) (driver.Conn, error) {
+	if dc, ok := f.d.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(f.dsn(addr))
+		if err != nil {
+			return nil, err
+		}
+
+		return c.Connect(ctx)
+	}
+
+	return f.d.Open(f.dsn(addr))
+}
+
+func (f *factory) Close(c driver.Conn) error {
+	return c.Close()
+}
+
+func (f *factory) Validate(c driver.Conn) error {
+	if v, ok := c.(driver.Validator); ok && !v.IsValid() {
+		return errors.New("connection is invalid")
+	}
+
+	return nil
+}
+
+// Connector implements driver.Connector interface.
+type Connector struct {
+	pool goconnpool.Pool[driver.Conn]
+	d    driver.Driver
+}
+
+// NewConnector creates driver.Connector which takes connections from the pool passed.
+//
+// Connection is owned by database/sql until it is closed: database/sql pools connections by itself.
+// Use Config.MaxConnsPerServer to limit the number of connections per replica.
+func NewConnector(p goconnpool.Pool[driver.Conn], d driver.Driver) *Connector {
+	return &Connector{
+		pool: p,
+		d:    d,
+	}
+}
+
+// Connect implements driver.Connector interface.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	r, err := c.pool.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{Conn: r.Value(), r: r}, nil
+}
+
+// Driver implements driver.Connector interface.
+func (c *Connector) Driver() driver.Driver {
+	return c.d
+}
+
+// conn releases the pool slot on Close call.
+// Optional interfaces of the original connection are proxied.
+type conn struct {
+	driver.Conn
+	r goconnpool.Resource[driver.Conn]
+}
+
+func (c *conn) Close() error {
+	return c.r.Close()
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return pc.PrepareContext(ctx, query)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.Conn.Prepare(query)
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bc, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bc.BeginTx(ctx, opts)
+	}
+
+	if opts.Isolation != 0 || opts.ReadOnly {
+		return nil, errors.New("driver doesn't support non-default transaction options")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.Conn.Begin() // nolint:staticcheck
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if ec, ok := c.Conn.(driver.ExecerContext); ok {
+		return ec.ExecContext(ctx, query, args)
+	}
+
+	return nil, driver.ErrSkip
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if qc, ok := c.Conn.(driver.QueryerContext); ok {
+		return qc.QueryContext(ctx, query, args)
+	}
+
+	return nil, driver.ErrSkip
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if sr, ok := c.Conn.(driver.SessionResetter); ok {
+		return sr.ResetSession(ctx)
+	}
+
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+
+	return driver.ErrSkip
+}
+
+// Dialer could be used as dial function of the drivers which accept custom dial functions.
+// Its DialContext method has the same signature as net.Dialer.DialContext has.
+//
+//	dialer := &sqlpool.Dialer{Pool: p, AnyServer: true} // DSN is "user@replicas(any)/db"
+//	mysql.RegisterDialContext("replicas", func(ctx context.Context, addr string) (net.Conn, error) {
+//		return dialer.DialContext(ctx, "tcp", addr)
+//	})
+type Dialer struct {
+	Pool goconnpool.ConnPool
+
+	// AnyServer allows the pool to choose the server if addr passed into DialContext isn't registered
+	// (e.g. DSN contains some placeholder host). goconnpool.ErrUnknownServer is returned for such addresses
+	// by default.
+	AnyServer bool
+}
+
+// DialContext opens the connection using the pool.
+//
+// If addr is the address of the registered server, connection is opened to this server
+// (see goconnpool.ConnPool.OpenConnToServer). Otherwise goconnpool.ErrUnknownServer is returned
+// unless AnyServer is set: the server is chosen by the pool then, addr (and network) is ignored.
+//
+// Connection is owned by the driver until it is closed.
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	cn, err := d.Pool.OpenConnToServer(ctx, addr)
+	if d.AnyServer && errors.Cause(err) == goconnpool.ErrUnknownServer {
+		cn, err = d.Pool.OpenConn(ctx)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cn, nil
+}
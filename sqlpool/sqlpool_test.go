@@ -0,0 +1,112 @@
+package sqlpool
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/derElektrobesen/goconnpool"
+	"github.com/derElektrobesen/goconnpool/internal/pooltest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type testDriver struct {
+	opened chan string
+}
+
+func (d *testDriver) Open(dsn string) (driver.Conn, error) {
+	if dsn == "db://bad" {
+		return nil, fmt.Errorf("connection refused")
+	}
+
+	d.opened <- dsn
+	return &testConn{}, nil
+}
+
+type testConn struct {
+	driver.Conn
+	closed bool
+}
+
+func (c *testConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func (c *testConn) Ping(ctx context.Context) error {
+	return nil
+}
+
+func TestConnector(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	drv := &testDriver{opened: make(chan string, 10)}
+
+	p := goconnpool.NewPool[driver.Conn](goconnpool.Config{
+		MaxRPS:                 math.MaxInt32,
+		InitialBackoffInterval: time.Hour,
+		MaxBackoffInterval:     time.Hour,
+	}, NewFactory(drv, func(addr string) string {
+		return "db://" + addr
+	}))
+
+	pooltest.RegisterServers(t, p, pooltest.Bad, pooltest.OK)
+
+	c := NewConnector(p, drv)
+	ass.Equal(drv, c.Driver())
+
+	db := sql.OpenDB(c)
+	defer db.Close()
+
+	db.SetMaxIdleConns(0)
+
+	// "bad" replica is marked down
+	ass.NoError(db.PingContext(context.Background()))
+	ass.Equal("db://ok", <-drv.opened)
+
+	ass.NoError(db.PingContext(context.Background()))
+	ass.Equal("db://ok", <-drv.opened)
+
+	st := p.Stats()
+	ass.False(st.Servers[0].Up)
+	ass.Equal(0, st.Servers[1].OpenConns)
+	ass.EqualValues(2, st.Servers[1].Dials)
+}
+
+func TestDialer(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	p := goconnpool.NewConnPool(goconnpool.Config{
+		MaxRPS: math.MaxInt32,
+		Dialer: pooltest.Dialer{},
+	})
+
+	pooltest.RegisterServers(t, p, "a", "b")
+
+	d := &Dialer{Pool: p}
+
+	cn, err := d.DialContext(context.Background(), "tcp", "b")
+	ass.NoError(err)
+	ass.Equal("b", cn.(goconnpool.Conn).ServerAddr())
+
+	// unknown addresses aren't dialed unless AnyServer is set
+	_, err = d.DialContext(context.Background(), "tcp", "replicas")
+	ass.Equal(goconnpool.ErrUnknownServer, errors.Cause(err))
+
+	d.AnyServer = true
+	cn, err = d.DialContext(context.Background(), "tcp", "replicas")
+	ass.NoError(err)
+	ass.Equal("a", cn.(goconnpool.Conn).ServerAddr())
+	ass.NoError(cn.Close())
+
+	st := p.Stats()
+	ass.Equal(0, st.Servers[0].OpenConns)
+	ass.Equal(1, st.Servers[1].InUseConns)
+}
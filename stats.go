@@ -0,0 +1,71 @@
+package goconnpool
+
+import "time"
+
+// Stats holds the snapshot of the pool state returned by ConnPool.Stats().
+type Stats struct {
+	// Servers contains the state of each registered server in the registration order.
+	Servers []ServerStats
+}
+
+// ServerStats holds the snapshot of one registered server state.
+type ServerStats struct {
+	// Address is the address passed into RegisterServer call.
+	Address string
+
+	// Limits holds the effective configuration of the server (see ServerOption).
+	Limits ServerLimits
+
+	// Up is false if the server was marked down with ConnPool.SetServerMode() call or if the last dial to
+	// the server was failed and the server is waiting for backoff interval.
+	Up bool
+
+	// Mode is the mode set with ConnPool.SetServerMode() call.
+	Mode ServerMode
+
+	// NextRetry is the time when the server which is not Up could be dialed again.
+	NextRetry time.Time
+
+	// OpenConns is the number of opened connections (both idle and in use).
+	OpenConns int
+
+	// IdleConns is the number of connections returned into pool and ready to be reused.
+	IdleConns int
+
+	// InUseConns is the number of connections currently owned by the user.
+	InUseConns int
+
+	// ActiveStreams is the number of leases of the multiplexed connections (see Config.MaxStreamsPerConn).
+	ActiveStreams int
+
+	// Dials is the total number of dials made to the server.
+	Dials uint64
+
+	// DialErrors is the total number of failed dials.
+	DialErrors uint64
+
+	// BytesRead and BytesWritten are the total numbers of bytes transferred through the server connections.
+	// Counted only if CountBytes wrapper is used (see Config.ConnWrapper).
+	BytesRead    uint64
+	BytesWritten uint64
+
+	// RatelimitHits is the total number of connection requests rejected because of MaxRPS,
+	// MaxConnsPerServer or backoff limits.
+	RatelimitHits uint64
+
+	// Borrowed contains connections currently owned by the user ordered by ID.
+	Borrowed []BorrowedConnStats
+}
+
+// BorrowedConnStats holds information about the connection owned by the user.
+type BorrowedConnStats struct {
+	// ID is the unique identifier of the connection.
+	ID uint64
+
+	// BorrowedAt is the time when the connection was returned by one of OpenConn* calls.
+	BorrowedAt time.Time
+
+	// Stack is the stack trace of the OpenConn* call returned the connection.
+	// Filled only if leaks detection is enabled (see Config.LeakThreshold).
+	Stack string
+}
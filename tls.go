@@ -0,0 +1,121 @@
+package goconnpool
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// TLSDialer is the implementation of Dialer interface for TLS servers.
+// TCP connection is established using TCPDialer (see WithDialer) and TLS handshake is performed over it.
+//
+// Handshake is performed within Config.ConnectTimeout. Dial returns *tls.Conn: use
+//
+//	cn.OriginalConn().(*tls.Conn).ConnectionState()
+//
+// to inspect the negotiated parameters.
+type TLSDialer struct {
+	base Dialer
+
+	cfg         *tls.Config
+	serverNames map[string]string
+
+	once sync.Once
+}
+
+// NewTLSDialer creates TLSDialer using TLS config passed (it could be nil).
+//
+// ServerName (SNI) is set per registered address: it is taken from serverNames map if the address is found there,
+// else cfg.ServerName is used if it is set, else the host part of the address is used.
+//
+// If cfg.ClientSessionCache is nil, the LRU cache shared across all servers is used for TLS sessions resumption.
+func NewTLSDialer(cfg *tls.Config, serverNames map[string]string) *TLSDialer {
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+
+	return &TLSDialer{
+		cfg:         cfg.Clone(),
+		serverNames: serverNames,
+	}
+}
+
+// WithDialer sets the dialer used to establish the underlying connection (like ProxyDialer).
+// TCPDialer is used by default.
+func (d *TLSDialer) WithDialer(base Dialer) *TLSDialer {
+	d.base = base
+	return d
+}
+
+// Dial dials some TLS server and performs the handshake.
+func (d *TLSDialer) Dial(ctx context.Context, address string) (net.Conn, error) {
+	d.once.Do(func() {
+		if d.base == nil {
+			d.base = &TCPDialer{}
+		}
+
+		if d.cfg == nil {
+			d.cfg = &tls.Config{}
+		}
+
+		if d.cfg.ClientSessionCache == nil {
+			d.cfg.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+		}
+	})
+
+	cfg := d.cfg.Clone() // session cache is shared between clones
+	cfg.ServerName = d.serverName(address)
+
+	cn, err := d.base.Dial(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsCn := tls.Client(cn, cfg)
+	if err := tlsCn.HandshakeContext(ctx); err != nil {
+		cn.Close() // nolint:errcheck
+		return nil, errors.Wrapf(err, "TLS handshake with %s failed", address)
+	}
+
+	return tlsCn, nil
+}
+
+// ValidateAddress implements AddressValidator interface.
+func (d *TLSDialer) ValidateAddress(address string) error {
+	var base Dialer = &TCPDialer{}
+	if d.base != nil {
+		base = d.base
+	}
+
+	if v, ok := base.(AddressValidator); ok {
+		return v.ValidateAddress(address)
+	}
+
+	return nil
+}
+
+func (d *TLSDialer) serverName(address string) string {
+	if name, ok := d.serverNames[address]; ok {
+		return name
+	}
+
+	if d.cfg.ServerName != "" {
+		return d.cfg.ServerName
+	}
+
+	network, address, err := parseAddress(address)
+	if err != nil || strings.HasPrefix(network, "unix") {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+
+	return host
+}
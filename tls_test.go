@@ -0,0 +1,90 @@
+package goconnpool
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSDialer(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok") // nolint:errcheck
+	}))
+	srv.StartTLS()
+	defer srv.Close()
+
+	addr := srv.Listener.Addr().String()
+	clientCfg := srv.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	clientCfg.MaxVersion = tls.VersionTLS12 // session ticket is received during the handshake
+
+	p := NewConnPool(Config{
+		MaxRPS:            math.MaxInt32,
+		MaxConnsPerServer: 2,
+		Dialer: NewTLSDialer(clientCfg, map[string]string{
+			addr: "example.com", // test certificate is issued for example.com and 127.0.0.1
+		}),
+	})
+	ass.NoError(p.RegisterServer(addr))
+
+	st := func(cn Conn) tls.ConnectionState {
+		return cn.OriginalConn().(*tls.Conn).ConnectionState()
+	}
+
+	cn1, err := p.OpenConn(context.Background())
+	ass.NoError(err)
+	ass.Equal("example.com", st(cn1).ServerName)
+	ass.True(st(cn1).HandshakeComplete)
+	ass.False(st(cn1).DidResume)
+
+	cn2, err := p.OpenConn(context.Background())
+	ass.NoError(err)
+	ass.True(st(cn2).DidResume) // session cache is shared
+
+	ass.NoError(cn1.Close())
+	ass.NoError(cn2.Close())
+
+	// certificate isn't valid for this name
+	d := NewTLSDialer(clientCfg, map[string]string{addr: "unknown.org"})
+	_, err = d.Dial(context.Background(), addr)
+	ass.Error(err)
+
+	// server name is taken from address: certificate is verified against 127.0.0.1
+	d = NewTLSDialer(clientCfg, nil)
+	cn, err := d.Dial(context.Background(), addr)
+	ass.NoError(err)
+	ass.NoError(cn.Close())
+}
+
+func TestTLSDialerHandshakeTimeout(t *testing.T) {
+	t.Parallel()
+	ass := require.New(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	ass.NoError(err)
+	defer l.Close()
+
+	go func() {
+		cn, err := l.Accept()
+		if err == nil {
+			defer cn.Close()
+			time.Sleep(time.Second) // handshake is never answered
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = (&TLSDialer{}).Dial(ctx, l.Addr().String())
+	ass.Error(err)
+}